@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"github.com/libvirt/libvirt-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var blockJobTypes = map[libvirt.DomainBlockJobType]string{
+	libvirt.DOMAIN_BLOCK_JOB_TYPE_PULL:          "pull",
+	libvirt.DOMAIN_BLOCK_JOB_TYPE_COPY:          "copy",
+	libvirt.DOMAIN_BLOCK_JOB_TYPE_COMMIT:        "commit",
+	libvirt.DOMAIN_BLOCK_JOB_TYPE_ACTIVE_COMMIT: "active_commit",
+	libvirt.DOMAIN_BLOCK_JOB_TYPE_BACKUP:        "backup",
+}
+
+type blockJobCollector struct {
+	info      *prometheus.Desc
+	cursor    *prometheus.Desc
+	end       *prometheus.Desc
+	bandwidth *prometheus.Desc
+	ready     *prometheus.Desc
+
+	filter *diskFilter
+}
+
+func init() {
+	registerCollector("blockjob", newBlockJobCollector)
+}
+
+func newBlockJobCollector() (Collector, error) {
+	c := &blockJobCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "block_job_info"),
+			"Block job running on the disk, always 1.",
+			domainLabelNames("target_device", "source", "backing_index", "type"), nil),
+		cursor: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "block_job_cursor"),
+			"Progress of the block job, in the units of block_job_end.",
+			domainLabelNames(diskLabelNames...), nil),
+		end: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "block_job_end"),
+			"Value of block_job_cursor at which the block job is complete, it may grow while the guest writes.",
+			domainLabelNames(diskLabelNames...), nil),
+		bandwidth: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "block_job_bandwidth_bytes_per_second"),
+			"Bandwidth limit of the block job, 0 if unlimited.",
+			domainLabelNames(diskLabelNames...), nil),
+		ready: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "block_job_ready"),
+			"Whether a copy or active commit job mirrors all writes and can be pivoted.",
+			domainLabelNames(diskLabelNames...), nil),
+	}
+	c.filter = newDiskFilter()
+
+	return c, nil
+}
+
+func (c *blockJobCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
+	if dom.xmlErr != nil {
+		return dom.xmlErr
+	}
+	// jobs belong to the top layer of a disk, which comes first
+	seen := map[string]bool{}
+	for _, v := range stats.Block {
+		if seen[v.Name] || !c.filter.reportName(dom, v.Name) {
+			continue
+		}
+		seen[v.Name] = true
+
+		job, err := stats.Domain.GetBlockJobInfo(v.Name, libvirt.DOMAIN_BLOCK_JOB_INFO_BANDWIDTH_BYTES)
+		if err != nil {
+			return err
+		}
+		// all zero without a job
+		typ, ok := blockJobTypes[job.Type]
+		if !ok {
+			continue
+		}
+		labels := blockLabels(dom, v, true)
+
+		ch <- prometheus.MustNewConstMetric(c.info,
+			prometheus.GaugeValue,
+			1,
+			blockLabels(dom, v, true, typ)...)
+		ch <- prometheus.MustNewConstMetric(c.cursor,
+			prometheus.GaugeValue,
+			float64(job.Cur),
+			labels...)
+		ch <- prometheus.MustNewConstMetric(c.end,
+			prometheus.GaugeValue,
+			float64(job.End),
+			labels...)
+		ch <- prometheus.MustNewConstMetric(c.bandwidth,
+			prometheus.GaugeValue,
+			float64(job.Bandwidth),
+			labels...)
+		// cur == end doesn't mean ready, libvirt marks the mirror ready on
+		// the READY event of qemu
+		disk, _ := dom.xml.Disk(v.Name)
+		ch <- prometheus.MustNewConstMetric(c.ready,
+			prometheus.GaugeValue,
+			boolFloat(disk.Mirror != nil && disk.Mirror.Ready != ""),
+			labels...)
+	}
+	return nil
+}
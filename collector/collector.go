@@ -2,10 +2,14 @@ package collector
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/libvirt/libvirt-go"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"prometheus_libvirt_exporter/collector/qga"
+	"prometheus_libvirt_exporter/internal"
 	"sync"
 	"time"
 )
@@ -13,19 +17,55 @@ import (
 const namespace = "libvirt"
 
 var (
-	factories          = make(map[string]Collector)
+	compatV1Metrics = kingpin.Flag(
+		"compat.v1-metrics",
+		"Also expose the metrics renamed in v2 under their v1 names, types and units.",
+	).Bool()
+
+	// returned by the factory of an opt-in collector which isn't enabled
+	errCollectorDisabled = errors.New("collector disabled")
+
+	factories          = make(map[string]func() (Collector, error))
 	scrapeDurationDesc = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "scrape", "collector_duration_seconds"),
-		"node_exporter: Duration of a collector scrape.",
-		[]string{"domain"},
+		"Duration of a collector run for a domain, domain is empty for host collectors.",
+		[]string{"domain", "collector"},
 		nil,
 	)
 	scrapeSuccessDesc = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "scrape", "collector_success"),
-		"node_exporter: Whether a collector succeeded.",
-		[]string{"domain"},
+		"Whether a collector run for a domain succeeded, domain is empty for host collectors.",
+		[]string{"domain", "collector"},
+		nil,
+	)
+	upDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "up"),
+		"Whether the connection to libvirt is up.",
+		nil,
 		nil,
 	)
+	collectorErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "collector",
+		Name:      "errors_total",
+		Help:      "Number of errors returned by a collector, by libvirt error code or guest agent error class.",
+	}, []string{"collector", "reason"})
+
+	libvirtErrorReasons = map[libvirt.ErrorNumber]string{
+		libvirt.ERR_INTERNAL_ERROR:        "internal_error",
+		libvirt.ERR_NO_SUPPORT:            "no_support",
+		libvirt.ERR_NO_CONNECT:            "no_connect",
+		libvirt.ERR_INVALID_CONN:          "invalid_conn",
+		libvirt.ERR_OPERATION_FAILED:      "operation_failed",
+		libvirt.ERR_SYSTEM_ERROR:          "system_error",
+		libvirt.ERR_RPC:                   "rpc",
+		libvirt.ERR_NO_DOMAIN:             "no_domain",
+		libvirt.ERR_OPERATION_INVALID:     "operation_invalid",
+		libvirt.ERR_OPERATION_TIMEOUT:     "operation_timeout",
+		libvirt.ERR_ARGUMENT_UNSUPPORTED:  "argument_unsupported",
+		libvirt.ERR_OPERATION_UNSUPPORTED: "operation_unsupported",
+		libvirt.ERR_AGENT_UNRESPONSIVE:    "agent_unresponsive",
+	}
 )
 
 type Commands struct {
@@ -46,95 +86,232 @@ type rpcSet struct {
 	GuestExec     bool
 }
 
+// domainMeta is what the collectors share about a domain during one scrape.
+type domainMeta struct {
+	uuid   string
+	rs     rpcSet
+	xml    internal.DomainXML
+	xmlErr error
+	// values of the configured labels
+	infoLabels   []string
+	metricLabels []string
+
+	statOnce sync.Once
+	stat     internal.Stat
+	statErr  error
+}
+
+// labels returns the label values of a per-domain series, see domainLabelNames.
+func (d *domainMeta) labels(values ...string) []string {
+	ret := append([]string{d.uuid}, d.metricLabels...)
+	return append(ret, values...)
+}
+
+// guestStat reads /proc/stat in the guest once per scrape, it is shared by the
+// cpu and guest collectors.
+func (d *domainMeta) guestStat(domain *libvirt.Domain) (internal.Stat, error) {
+	d.statOnce.Do(func() {
+		data, err := qga.ReadFile(domain, "/proc/stat")
+		if err != nil {
+			d.statErr = err
+			return
+		}
+		d.stat, d.statErr = internal.GetStat(data)
+	})
+	return d.stat, d.statErr
+}
+
 type Collector interface {
-	Update(ch chan<- prometheus.Metric, dom *libvirt.DomainStats, uuid string, rs rpcSet) error
+	Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error
+}
+
+// StatsCollector is implemented by collectors which need stats types of
+// GetAllDomainStats besides the ones always requested.
+type StatsCollector interface {
+	Collector
+	StatsTypes() libvirt.DomainStatsTypes
+}
+
+// InactiveCollector is implemented by collectors which also report on domains
+// that are not running, all other collectors only see active domains.
+type InactiveCollector interface {
+	Collector
+	CollectsInactive()
+}
+
+// HostCollector is implemented by collectors which report on the host
+// rather than on each domain, UpdateHost is run once per scrape instead of
+// Update.
+type HostCollector interface {
+	Collector
+	UpdateHost(ch chan<- prometheus.Metric, conn *libvirt.Connect) error
 }
 
 type LibvirtCollector struct {
-	Uri        string
+	Conn       *Connection
 	Collectors map[string]Collector
-	logger     logrus.Logger
+	logger     *logrus.Logger
+}
+
+// v1Desc describes a metric of the v1 naming scheme, it is only emitted next
+// to its v2 replacement with --compat.v1-metrics.
+func v1Desc(subsystem, name string, labels []string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, name),
+		"Deprecated v1 metric, only exposed with --compat.v1-metrics.",
+		labels, nil)
 }
 
 func registerCollector(collector string, factory func() (Collector, error)) {
-	c, err := factory()
-	if err != nil {
-		logrus.Debug("failed to init collector ", collector)
-		return
-	}
-	factories[collector] = c
+	factories[collector] = factory
 }
 
-func NewLibvirtCollector(uri string, logger logrus.Logger, filters ...string) (*LibvirtCollector, error) {
+// NewLibvirtCollector builds every enabled collector. Collectors keep state
+// between scrapes, so they are built once and filtered per request with
+// Filter.
+func NewLibvirtCollector(conn *Connection, logger *logrus.Logger) (*LibvirtCollector, error) {
+	// collectors build their descriptors with the configured labels
+	if err := loadDomainLabels(); err != nil {
+		return nil, fmt.Errorf("couldn't load domain labels: %s", err)
+	}
+
 	collectors := make(map[string]Collector)
-	if len(filters) == 0 {
-		collectors = factories
-	} else {
-		for _, filter := range filters {
-			_, exist := factories[filter]
-			if !exist {
-				return nil, fmt.Errorf("missing collector: %s", filter)
-			}
-			collectors[filter] = factories[filter]
+	for name, factory := range factories {
+		c, err := factory()
+		if err == errCollectorDisabled {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create collector %s: %w", name, err)
 		}
+		collectors[name] = c
 	}
 
 	return &LibvirtCollector{
-		Uri:        uri,
+		Conn:       conn,
 		Collectors: collectors,
 		logger:     logger,
 	}, nil
 }
 
+// Filter returns a LibvirtCollector sharing the named collectors of l.
+// Collectors which are known but not enabled are left out.
+func (l *LibvirtCollector) Filter(filters ...string) (*LibvirtCollector, error) {
+	collectors := make(map[string]Collector)
+	for _, filter := range filters {
+		if _, exist := factories[filter]; !exist {
+			return nil, fmt.Errorf("missing collector: %s", filter)
+		}
+		if c, ok := l.Collectors[filter]; ok {
+			collectors[filter] = c
+		}
+	}
+
+	return &LibvirtCollector{
+		Conn:       l.Conn,
+		Collectors: collectors,
+		logger:     l.logger,
+	}, nil
+}
+
 func (l *LibvirtCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- scrapeDurationDesc
 	ch <- scrapeSuccessDesc
+	ch <- upDesc
+	collectorErrors.Describe(ch)
+	l.Conn.Describe(ch)
 }
 
 func (l *LibvirtCollector) excute(ch chan<- prometheus.Metric, domStats libvirt.DomainStats) {
-	var success float64
-	begin := time.Now()
 	uuid, _ := domStats.Domain.GetUUIDString()
 
-	frSet, _ := GetRpcSet(domStats.Domain)
+	active, _ := domStats.Domain.IsActive()
+	frSet := rpcSet{false, false}
+	if active {
+		frSet, _ = GetRpcSet(domStats.Domain)
+	}
+
+	meta := &domainMeta{uuid: uuid, rs: frSet}
+	meta.xml, meta.xmlErr = getDomainXML(domStats.Domain)
+	if len(infoLabels) > 0 && meta.xmlErr == nil {
+		node, err := internal.GetXMLNode(meta.xml.Raw)
+		if err != nil {
+			l.logger.Debug("uuid=", uuid, " error=", err)
+		}
+		meta.infoLabels = labelValues(node, infoLabels)
+		meta.metricLabels = labelValues(node, metricLabels)
+	} else {
+		meta.infoLabels = make([]string, len(infoLabels))
+		meta.metricLabels = make([]string, len(metricLabels))
+	}
 
 	wg := sync.WaitGroup{}
 	for name, c := range l.Collectors {
+		if _, ok := c.(HostCollector); ok {
+			continue
+		}
+		if _, ok := c.(InactiveCollector); !ok && !active {
+			continue
+		}
 		wg.Add(1)
 		go func(n string, c Collector) {
-			err := c.Update(ch, &domStats, uuid, frSet)
-			if err != nil {
-				l.logger.Debug("uuid=", uuid, " collector=", n, " error=", err)
-				success = 0
-			} else {
-				success = 1
-			}
-			wg.Done()
+			defer wg.Done()
+			l.run(ch, uuid, n, func() error {
+				return c.Update(ch, &domStats, meta)
+			})
 		}(name, c)
 	}
 	wg.Wait()
+}
 
+// run times a collector and reports whether it succeeded, domain is empty
+// for host collectors.
+func (l *LibvirtCollector) run(ch chan<- prometheus.Metric, domain, name string, update func() error) {
+	var success float64
+	begin := time.Now()
+	err := update()
 	duration := time.Since(begin)
-	ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, duration.Seconds(), uuid)
-	ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, success, uuid)
+	if err != nil {
+		reason := errorReason(err)
+		collectorErrors.WithLabelValues(name, reason).Inc()
+		l.logger.Debug("uuid=", domain, " collector=", name, " reason=", reason, " error=", err)
+		success = 0
+	} else {
+		success = 1
+	}
+	ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, duration.Seconds(), domain, name)
+	ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, success, domain, name)
 }
 
 func (l *LibvirtCollector) Collect(ch chan<- prometheus.Metric) {
-	conn, err := libvirt.NewConnect(l.Uri)
+	defer l.Conn.Collect(ch)
+	defer collectorErrors.Collect(ch)
+
+	conn, err := l.Conn.Get()
 	if err != nil {
-		l.logger.Fatalf("failed to connect to %s.", l.Uri)
+		l.logger.Warn(err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0)
+		return
 	}
 	defer conn.Close()
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1)
 
 	// get all doms stats
+	statsTypes := libvirt.DOMAIN_STATS_STATE |
+		libvirt.DOMAIN_STATS_CPU_TOTAL |
+		libvirt.DOMAIN_STATS_VCPU |
+		libvirt.DOMAIN_STATS_BALLOON |
+		libvirt.DOMAIN_STATS_BLOCK |
+		libvirt.DOMAIN_STATS_INTERFACE
+	for _, c := range l.Collectors {
+		if sc, ok := c.(StatsCollector); ok {
+			statsTypes |= sc.StatsTypes()
+		}
+	}
 	statsAll, err := conn.GetAllDomainStats([]*libvirt.Domain{},
-		libvirt.DOMAIN_STATS_STATE|
-			libvirt.DOMAIN_STATS_CPU_TOTAL|
-			libvirt.DOMAIN_STATS_BALLOON|
-			libvirt.DOMAIN_STATS_BLOCK|
-			libvirt.DOMAIN_STATS_INTERFACE,
+		statsTypes,
 		//libvirt.CONNECT_GET_ALL_DOMAINS_STATS_NOWAIT, // maybe in future
-		libvirt.CONNECT_GET_ALL_DOMAINS_STATS_ACTIVE)
+		libvirt.CONNECT_GET_ALL_DOMAINS_STATS_BACKING)
 
 	defer func(statsAll []libvirt.DomainStats) {
 		for _, domStat := range statsAll {
@@ -143,10 +320,21 @@ func (l *LibvirtCollector) Collect(ch chan<- prometheus.Metric) {
 	}(statsAll)
 
 	if err != nil {
-		l.logger.Warn("failed to get stats.")
+		l.logger.Warn("failed to get stats: ", err)
 	}
 
 	wg := sync.WaitGroup{}
+	for name, c := range l.Collectors {
+		if hc, ok := c.(HostCollector); ok {
+			wg.Add(1)
+			go func(n string, hc HostCollector) {
+				defer wg.Done()
+				l.run(ch, "", n, func() error {
+					return hc.UpdateHost(ch, conn)
+				})
+			}(name, hc)
+		}
+	}
 	for _, stats := range statsAll {
 		wg.Add(1)
 		go func(s libvirt.DomainStats) {
@@ -157,6 +345,54 @@ func (l *LibvirtCollector) Collect(ch chan<- prometheus.Metric) {
 	wg.Wait()
 }
 
+func getDomainXML(dom *libvirt.Domain) (internal.DomainXML, error) {
+	xmlDesc, err := dom.GetXMLDesc(0)
+	if err != nil {
+		return internal.DomainXML{}, err
+	}
+	return internal.GetDomainXML(xmlDesc)
+}
+
+// errorReason returns the reason label of libvirt_collector_errors_total.
+func errorReason(err error) string {
+	var qgaErr *qga.Error
+	if errors.As(err, &qgaErr) {
+		return qgaErr.Class
+	}
+	var virErr libvirt.Error
+	if errors.As(err, &virErr) {
+		if reason, ok := libvirtErrorReasons[virErr.Code]; ok {
+			return reason
+		}
+		return fmt.Sprintf("libvirt_error_%d", virErr.Code)
+	}
+	return "other"
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// isUnsupported tells whether libvirt or the hypervisor lacks an API.
+func isUnsupported(err error) bool {
+	var virErr libvirt.Error
+	if !errors.As(err, &virErr) {
+		return false
+	}
+	return virErr.Code == libvirt.ERR_NO_SUPPORT ||
+		virErr.Code == libvirt.ERR_OPERATION_UNSUPPORTED ||
+		virErr.Code == libvirt.ERR_ARGUMENT_UNSUPPORTED
+}
+
+// isLibvirtError tells whether err is a libvirt error with the code.
+func isLibvirtError(err error, code libvirt.ErrorNumber) bool {
+	var virErr libvirt.Error
+	return errors.As(err, &virErr) && virErr.Code == code
+}
+
 func GetRpcSet(dom *libvirt.Domain) (rpcSet, error) {
 	rs := rpcSet{false, false}
 	cmdSet, err := dom.QemuAgentCommand("{\"execute\":\"guest-info\"}", 1, 0)
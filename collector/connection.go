@@ -0,0 +1,190 @@
+package collector
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/libvirt/libvirt-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	reconnectMinBackoff = time.Second
+)
+
+var eventLoopOnce sync.Once
+
+// Connection is a long-lived libvirt connection shared by every scrape. It is
+// checked with keepalive messages and re-opened with an exponential backoff
+// once libvirtd goes away.
+type Connection struct {
+	uri               string
+	keepaliveInterval int
+	keepaliveCount    uint
+	maxBackoff        time.Duration
+	logger            *logrus.Logger
+
+	mu      sync.Mutex
+	conn    *libvirt.Connect
+	closed  *int32 // set by the close callback of conn
+	backoff time.Duration
+	retryAt time.Time
+
+	errors *prometheus.CounterVec
+}
+
+func NewConnection(uri string, keepaliveInterval int, keepaliveCount uint, maxBackoff time.Duration, logger *logrus.Logger) *Connection {
+	// keepalive needs a running event loop, it must be registered before the first connect
+	eventLoopOnce.Do(func() {
+		if err := libvirt.EventRegisterDefaultImpl(); err != nil {
+			logger.Warn("failed to register event loop, keepalive disabled: ", err)
+			return
+		}
+		limit := maxBackoff
+		if limit < reconnectMinBackoff {
+			limit = reconnectMinBackoff
+		}
+		go func() {
+			// a persistent error would spin, back off like the reconnects
+			// and only warn about the first error in a row
+			var backoff time.Duration
+			for {
+				err := libvirt.EventRunDefaultImpl()
+				if err == nil {
+					backoff = 0
+					continue
+				}
+				if backoff == 0 {
+					logger.Warn("event loop error, keepalive may fail: ", err)
+					backoff = reconnectMinBackoff
+				} else {
+					logger.Debug("event loop error: ", err)
+					if backoff *= 2; backoff > limit {
+						backoff = limit
+					}
+				}
+				time.Sleep(backoff)
+			}
+		}()
+	})
+
+	return &Connection{
+		uri:               uri,
+		keepaliveInterval: keepaliveInterval,
+		keepaliveCount:    keepaliveCount,
+		maxBackoff:        maxBackoff,
+		logger:            logger,
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "connection",
+			Name:      "errors_total",
+			Help:      "Number of failed connects to libvirt and of connections closed by libvirt, by reason.",
+		}, []string{"reason"}),
+	}
+}
+
+// Get returns the shared connection, opening it first if needed. The caller
+// owns a reference to the returned connection and must Close it when done.
+func (c *Connection) Get() (*libvirt.Connect, error) {
+	conn, lost, err := c.get()
+	// libvirt holds its close callback lock while running the callback,
+	// unregister it without holding c.mu
+	if lost != nil {
+		lost.UnregisterCloseCallback()
+		lost.Close()
+	}
+	return conn, err
+}
+
+// get returns a referenced connection and the lost connection it replaced,
+// if any, which the caller has to release.
+func (c *Connection) get() (*libvirt.Connect, *libvirt.Connect, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var lost *libvirt.Connect
+	if c.conn != nil {
+		alive, err := c.conn.IsAlive()
+		if atomic.LoadInt32(c.closed) == 0 && err == nil && alive {
+			if err := c.conn.Ref(); err != nil {
+				return nil, nil, err
+			}
+			return c.conn, nil, nil
+		}
+		c.logger.Warn("connection to ", c.uri, " lost, reconnecting")
+		// in-flight scrapes keep their own references
+		lost = c.conn
+		c.conn = nil
+		c.closed = nil
+	}
+
+	if now := time.Now(); now.Before(c.retryAt) {
+		return nil, lost, fmt.Errorf("reconnect to %s backing off for %s", c.uri, c.retryAt.Sub(now).Round(time.Second))
+	}
+
+	conn, err := libvirt.NewConnect(c.uri)
+	if err != nil {
+		c.errors.WithLabelValues("connect").Inc()
+		c.backoff *= 2
+		if c.backoff < reconnectMinBackoff {
+			c.backoff = reconnectMinBackoff
+		}
+		if c.backoff > c.maxBackoff {
+			c.backoff = c.maxBackoff
+		}
+		c.retryAt = time.Now().Add(c.backoff)
+		return nil, lost, fmt.Errorf("failed to connect to %s: %w", c.uri, err)
+	}
+
+	if c.keepaliveInterval > 0 {
+		if err := conn.SetKeepAlive(c.keepaliveInterval, c.keepaliveCount); err != nil {
+			c.logger.Debug("failed to enable keepalive: ", err)
+		}
+	}
+	// libvirt-go passes a new Connect to the callback, so the flag of this
+	// connection is captured instead of comparing connections
+	closed := new(int32)
+	err = conn.RegisterCloseCallback(func(_ *libvirt.Connect, reason libvirt.ConnectCloseReason) {
+		// closing the connection from the callback is not allowed, Get drops it
+		c.errors.WithLabelValues(closeReason(reason)).Inc()
+		atomic.StoreInt32(closed, 1)
+	})
+	if err != nil {
+		c.logger.Debug("failed to register close callback: ", err)
+	}
+
+	c.logger.Info("connected to ", c.uri)
+	c.conn = conn
+	c.closed = closed
+	c.backoff = 0
+	c.retryAt = time.Time{}
+
+	if err := conn.Ref(); err != nil {
+		return nil, lost, err
+	}
+	return conn, lost, nil
+}
+
+func (c *Connection) Describe(ch chan<- *prometheus.Desc) {
+	c.errors.Describe(ch)
+}
+
+func (c *Connection) Collect(ch chan<- prometheus.Metric) {
+	c.errors.Collect(ch)
+}
+
+func closeReason(reason libvirt.ConnectCloseReason) string {
+	switch reason {
+	case libvirt.CONNECT_CLOSE_REASON_EOF:
+		return "eof"
+	case libvirt.CONNECT_CLOSE_REASON_KEEPALIVE:
+		return "keepalive"
+	case libvirt.CONNECT_CLOSE_REASON_CLIENT:
+		return "client"
+	default:
+		return "error"
+	}
+}
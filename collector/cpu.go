@@ -5,6 +5,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"prometheus_libvirt_exporter/collector/qga"
 	"prometheus_libvirt_exporter/internal"
+	"strconv"
 )
 
 const (
@@ -12,18 +13,23 @@ const (
 )
 
 type cpuCollector struct {
-	cpuCores         *prometheus.Desc
-	cpuSystemTime    *prometheus.Desc
-	cpuCpuTime       *prometheus.Desc
-	cpuUserTime      *prometheus.Desc
-	cpuLoad1         *prometheus.Desc
-	cpuLoad5         *prometheus.Desc
-	cpuLoad15        *prometheus.Desc
-	qgaCpuSystemTime *prometheus.Desc
-	qgaCpuCpuTime    *prometheus.Desc
-	qgaCpuUserTime   *prometheus.Desc
-	qgaCpuStealTime  *prometheus.Desc
-	qgaCpuIowait     *prometheus.Desc
+	cpuCores      *prometheus.Desc
+	cpuSystemTime *prometheus.Desc
+	cpuCpuTime    *prometheus.Desc
+	cpuUserTime   *prometheus.Desc
+	cpuLoad1      *prometheus.Desc
+	cpuLoad5      *prometheus.Desc
+	cpuLoad15     *prometheus.Desc
+	qgaCpuSeconds *prometheus.Desc
+
+	// v1 names, see --compat.v1-metrics
+	v1CpuSystemTime    *prometheus.Desc
+	v1CpuCpuTime       *prometheus.Desc
+	v1CpuUserTime      *prometheus.Desc
+	v1QgaCpuSystemTime *prometheus.Desc
+	v1QgaCpuUserTime   *prometheus.Desc
+	v1QgaCpuStealTime  *prometheus.Desc
+	v1QgaCpuIowait     *prometheus.Desc
 }
 
 func init() {
@@ -34,107 +40,118 @@ func newCPUCollector() (Collector, error) {
 	c := &cpuCollector{
 		cpuCores: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "cores"),
-			"",
-			[]string{"uuid"}, nil),
+			"Number of online vCPUs.",
+			domainLabelNames(), nil),
 
 		cpuSystemTime: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "system_time"),
-			"",
-			[]string{"uuid"}, nil),
+			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "system_seconds_total"),
+			"Seconds the domain spent in the host kernel.",
+			domainLabelNames(), nil),
 		cpuCpuTime: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "cpu_time"),
-			"",
-			[]string{"uuid"}, nil),
+			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "time_seconds_total"),
+			"Seconds of CPU time used by the domain.",
+			domainLabelNames(), nil),
 		cpuLoad1: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "load1"),
-			"",
-			[]string{"uuid"}, nil),
+			"1m load average in the guest.",
+			domainLabelNames(), nil),
 		cpuLoad5: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "load5"),
-			"",
-			[]string{"uuid"}, nil),
+			"5m load average in the guest.",
+			domainLabelNames(), nil),
 		cpuLoad15: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "load15"),
-			"",
-			[]string{"uuid"}, nil),
+			"15m load average in the guest.",
+			domainLabelNames(), nil),
 		cpuUserTime: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "user_time"),
-			"",
-			[]string{"uuid"}, nil),
-
-		qgaCpuSystemTime: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "qga_system_time"),
-			"",
-			[]string{"uuid"}, nil),
-		qgaCpuCpuTime: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "qga_cpu_time"),
-			"",
-			[]string{"uuid"}, nil),
-		qgaCpuUserTime: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "qga_user_time"),
-			"",
-			[]string{"uuid"}, nil),
-		qgaCpuStealTime: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "qga_steal_time"),
-			"",
-			[]string{"uuid"}, nil),
-		qgaCpuIowait: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "qga_iowait"),
-			"",
-			[]string{"uuid"}, nil),
+			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "user_seconds_total"),
+			"Seconds the domain spent in host user space.",
+			domainLabelNames(), nil),
+
+		qgaCpuSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "guest_seconds_total"),
+			"Seconds the guest CPUs spent in each mode, read from /proc/stat in the guest. cpu is \"total\" for the sum of all CPUs.",
+			domainLabelNames("cpu", "mode"), nil),
+
+		v1CpuSystemTime:    v1Desc(cpuCollectorSubsystem, "system_time", domainLabelNames()),
+		v1CpuCpuTime:       v1Desc(cpuCollectorSubsystem, "cpu_time", domainLabelNames()),
+		v1CpuUserTime:      v1Desc(cpuCollectorSubsystem, "user_time", domainLabelNames()),
+		v1QgaCpuSystemTime: v1Desc(cpuCollectorSubsystem, "qga_system_time", domainLabelNames()),
+		v1QgaCpuUserTime:   v1Desc(cpuCollectorSubsystem, "qga_user_time", domainLabelNames()),
+		v1QgaCpuStealTime:  v1Desc(cpuCollectorSubsystem, "qga_steal_time", domainLabelNames()),
+		v1QgaCpuIowait:     v1Desc(cpuCollectorSubsystem, "qga_iowait", domainLabelNames()),
 	}
 
 	return c, nil
 }
 
-func (c *cpuCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, uuid string, rs rpcSet) error {
+func (c *cpuCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
 	cpuStats := stats.Cpu
-	info, err := stats.Domain.GetInfo()
+	cores, err := vcpuCount(stats)
 	if err != nil {
 		return err
 	}
+	// libvirt reports nanoseconds
 	ch <- prometheus.MustNewConstMetric(c.cpuSystemTime,
-		prometheus.GaugeValue,
-		float64(cpuStats.System),
-		uuid)
+		prometheus.CounterValue,
+		float64(cpuStats.System)/1e9,
+		dom.labels()...)
 	ch <- prometheus.MustNewConstMetric(c.cpuCpuTime,
-		prometheus.GaugeValue,
-		float64(cpuStats.Time),
-		uuid)
+		prometheus.CounterValue,
+		float64(cpuStats.Time)/1e9,
+		dom.labels()...)
 	ch <- prometheus.MustNewConstMetric(c.cpuUserTime,
-		prometheus.GaugeValue,
-		float64(cpuStats.User),
-		uuid)
+		prometheus.CounterValue,
+		float64(cpuStats.User)/1e9,
+		dom.labels()...)
+	if *compatV1Metrics {
+		ch <- prometheus.MustNewConstMetric(c.v1CpuSystemTime,
+			prometheus.GaugeValue,
+			float64(cpuStats.System),
+			dom.labels()...)
+		ch <- prometheus.MustNewConstMetric(c.v1CpuCpuTime,
+			prometheus.GaugeValue,
+			float64(cpuStats.Time),
+			dom.labels()...)
+		ch <- prometheus.MustNewConstMetric(c.v1CpuUserTime,
+			prometheus.GaugeValue,
+			float64(cpuStats.User),
+			dom.labels()...)
+	}
 
 	ch <- prometheus.MustNewConstMetric(c.cpuCores,
 		prometheus.GaugeValue,
-		float64(info.NrVirtCpu),
-		uuid)
+		float64(cores),
+		dom.labels()...)
 
-	if rs.GuestFileRead {
-		dataStat, err := qga.ReadFile(stats.Domain, "/proc/stat")
+	if dom.rs.GuestFileRead {
+		s, err := dom.guestStat(stats.Domain)
 		if err != nil {
 			return err
 		}
-		s, _ := internal.GetStat(dataStat)
-		//fmt.Printf("%+v\n", s)
+		c.updateGuestCPU(ch, dom, "total", s.CPUTotal)
+		for i, cpuStat := range s.CPU {
+			c.updateGuestCPU(ch, dom, strconv.Itoa(i), cpuStat)
+		}
 
-		ch <- prometheus.MustNewConstMetric(c.qgaCpuSystemTime,
-			prometheus.GaugeValue,
-			float64(s.CPUTotal.System),
-			uuid)
-		ch <- prometheus.MustNewConstMetric(c.qgaCpuStealTime,
-			prometheus.GaugeValue,
-			float64(s.CPUTotal.Steal),
-			uuid)
-		ch <- prometheus.MustNewConstMetric(c.qgaCpuIowait,
-			prometheus.GaugeValue,
-			float64(s.CPUTotal.Iowait),
-			uuid)
-		ch <- prometheus.MustNewConstMetric(c.qgaCpuUserTime,
-			prometheus.GaugeValue,
-			float64(s.CPUTotal.User),
-			uuid)
+		if *compatV1Metrics {
+			ch <- prometheus.MustNewConstMetric(c.v1QgaCpuSystemTime,
+				prometheus.GaugeValue,
+				float64(s.CPUTotal.System),
+				dom.labels()...)
+			ch <- prometheus.MustNewConstMetric(c.v1QgaCpuStealTime,
+				prometheus.GaugeValue,
+				float64(s.CPUTotal.Steal),
+				dom.labels()...)
+			ch <- prometheus.MustNewConstMetric(c.v1QgaCpuIowait,
+				prometheus.GaugeValue,
+				float64(s.CPUTotal.Iowait),
+				dom.labels()...)
+			ch <- prometheus.MustNewConstMetric(c.v1QgaCpuUserTime,
+				prometheus.GaugeValue,
+				float64(s.CPUTotal.User),
+				dom.labels()...)
+		}
 
 		dataLoad, err := qga.ReadFile(stats.Domain, "/proc/loadavg")
 		if err != nil {
@@ -144,19 +161,60 @@ func (c *cpuCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.Domain
 		if err != nil {
 			return err
 		}
-		//fmt.Printf("%+v\n", l)
 		ch <- prometheus.MustNewConstMetric(c.cpuLoad1,
 			prometheus.GaugeValue,
 			float64(l[0]),
-			uuid)
+			dom.labels()...)
 		ch <- prometheus.MustNewConstMetric(c.cpuLoad5,
 			prometheus.GaugeValue,
 			float64(l[1]),
-			uuid)
+			dom.labels()...)
 		ch <- prometheus.MustNewConstMetric(c.cpuLoad15,
 			prometheus.GaugeValue,
 			float64(l[2]),
-			uuid)
+			dom.labels()...)
 	}
 	return nil
 }
+
+func (c *cpuCollector) updateGuestCPU(ch chan<- prometheus.Metric, dom *domainMeta, cpu string, s internal.CPUStat) {
+	modes := []struct {
+		mode  string
+		value float64
+	}{
+		{"user", s.User},
+		{"nice", s.Nice},
+		{"system", s.System},
+		{"idle", s.Idle},
+		{"iowait", s.Iowait},
+		{"irq", s.IRQ},
+		{"softirq", s.SoftIRQ},
+		{"steal", s.Steal},
+		{"guest", s.Guest},
+		{"guest_nice", s.GuestNice},
+	}
+	for _, m := range modes {
+		ch <- prometheus.MustNewConstMetric(c.qgaCpuSeconds,
+			prometheus.CounterValue,
+			m.value,
+			dom.labels(cpu, m.mode)...)
+	}
+}
+
+// vcpuCount returns the number of online vCPUs, only these have a state.
+func vcpuCount(stats *libvirt.DomainStats) (uint, error) {
+	var count uint
+	for _, v := range stats.Vcpu {
+		if v.StateSet && v.State != libvirt.VCPU_OFFLINE {
+			count++
+		}
+	}
+	if count > 0 {
+		return count, nil
+	}
+	info, err := stats.Domain.GetInfo()
+	if err != nil {
+		return 0, err
+	}
+	return info.NrVirtCpu, nil
+}
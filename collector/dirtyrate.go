@@ -0,0 +1,120 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/libvirt/libvirt-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	dirtyrateCollectorSubsystem = "dirtyrate"
+)
+
+var (
+	dirtyrateInterval = kingpin.Flag(
+		"collector.dirtyrate.interval",
+		"Start a dirty rate calculation on running domains this often, 0 disables the dirtyrate collector.",
+	).Default("0s").Duration()
+	// libvirt accepts whole seconds from 1 to 60
+	dirtyrateCalcPeriod = secondsFlag(kingpin.Flag(
+		"collector.dirtyrate.calc-period",
+		"Time a dirty rate calculation samples the memory of the domain, whole seconds from 1s to 60s.",
+	).Default("1s"), time.Second, time.Minute)
+)
+
+var dirtyrateStatuses = map[libvirt.DomainDirtyRateStatus]string{
+	libvirt.DOMAIN_DIRTYRATE_UNSTARTED: "unstarted",
+	libvirt.DOMAIN_DIRTYRATE_MEASURING: "measuring",
+	libvirt.DOMAIN_DIRTYRATE_MEASURED:  "measured",
+}
+
+type dirtyrateCollector struct {
+	status    *prometheus.Desc
+	rate      *prometheus.Desc
+	period    *prometheus.Desc
+	startTime *prometheus.Desc
+}
+
+func init() {
+	registerCollector("dirtyrate", newDirtyrateCollector)
+}
+
+func newDirtyrateCollector() (Collector, error) {
+	if *dirtyrateInterval <= 0 {
+		return nil, errCollectorDisabled
+	}
+	c := &dirtyrateCollector{
+		status: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dirtyrateCollectorSubsystem, "calc_status"),
+			"Status of the last dirty rate calculation, always 1.",
+			domainLabelNames("status"), nil),
+		rate: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dirtyrateCollectorSubsystem, "mebibytes_per_second"),
+			"Memory dirtied by the domain in MiB/s, measured by the last calculation.",
+			domainLabelNames(), nil),
+		period: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dirtyrateCollectorSubsystem, "calc_period_seconds"),
+			"Time the last dirty rate calculation sampled the memory.",
+			domainLabelNames(), nil),
+		startTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dirtyrateCollectorSubsystem, "calc_start_timestamp_seconds"),
+			"Time the last dirty rate calculation was started.",
+			domainLabelNames(), nil),
+	}
+
+	return c, nil
+}
+
+func (c *dirtyrateCollector) StatsTypes() libvirt.DomainStatsTypes {
+	return libvirt.DOMAIN_STATS_DIRTYRATE
+}
+
+func (c *dirtyrateCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
+	d := stats.DirtyRate
+	if d == nil {
+		d = &libvirt.DomainStatsDirtyRate{}
+	}
+
+	status := libvirt.DOMAIN_DIRTYRATE_UNSTARTED
+	if d.CalcStatusSet {
+		status = libvirt.DomainDirtyRateStatus(d.CalcStatus)
+	}
+	name, ok := dirtyrateStatuses[status]
+	if !ok {
+		name = "unknown"
+	}
+	ch <- prometheus.MustNewConstMetric(c.status,
+		prometheus.GaugeValue,
+		1,
+		dom.labels(name)...)
+	if d.MegabytesPerSecondSet {
+		ch <- prometheus.MustNewConstMetric(c.rate,
+			prometheus.GaugeValue,
+			float64(d.MegabytesPerSecond),
+			dom.labels()...)
+	}
+	if d.CalcPeriodSet {
+		ch <- prometheus.MustNewConstMetric(c.period,
+			prometheus.GaugeValue,
+			float64(d.CalcPeriod),
+			dom.labels()...)
+	}
+	if d.CalcStartTimeSet {
+		ch <- prometheus.MustNewConstMetric(c.startTime,
+			prometheus.GaugeValue,
+			float64(d.CalcStartTime),
+			dom.labels()...)
+	}
+
+	// the start time of the last calculation paces the next one, so no
+	// state is kept across scrapes
+	if status == libvirt.DOMAIN_DIRTYRATE_MEASURING {
+		return nil
+	}
+	if d.CalcStartTimeSet && time.Since(time.Unix(d.CalcStartTime, 0)) < *dirtyrateInterval {
+		return nil
+	}
+	return stats.Domain.StartDirtyRateCalc(int(dirtyrateCalcPeriod.Seconds()), 0)
+}
@@ -3,24 +3,115 @@ package collector
 import (
 	"prometheus_libvirt_exporter/collector/qga"
 	"prometheus_libvirt_exporter/internal"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/libvirt/libvirt-go"
 	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 const (
 	diskCollectorSubsystem = "disk"
 )
 
+// labels of the block device series, backing_index is empty for the top
+// layer of a disk
+var diskLabelNames = []string{"target_device", "source", "backing_index"}
+
+var (
+	diskDeviceTypes = kingpin.Flag(
+		"collector.disk.device-types",
+		"Comma separated disk device types to report, of disk, cdrom, floppy and lun.",
+	).Default("disk,lun").String()
+	diskDeviceInclude = regexpFlag(kingpin.Flag(
+		"collector.disk.device-include",
+		"Regexp of target devices to report, e.g. vd.*.",
+	))
+	diskDeviceExclude = regexpFlag(kingpin.Flag(
+		"collector.disk.device-exclude",
+		"Regexp of target devices not to report.",
+	))
+)
+
+// diskFilter selects the disks reported by the disk collectors.
+type diskFilter struct {
+	deviceTypes   map[string]bool
+	deviceInclude *regexp.Regexp
+	deviceExclude *regexp.Regexp
+}
+
+func newDiskFilter() *diskFilter {
+	f := &diskFilter{
+		deviceTypes:   map[string]bool{},
+		deviceInclude: *diskDeviceInclude,
+		deviceExclude: *diskDeviceExclude,
+	}
+	for _, t := range strings.Split(*diskDeviceTypes, ",") {
+		f.deviceTypes[strings.TrimSpace(t)] = true
+	}
+	return f
+}
+
+// report tells whether the disk passes the device type and name filters.
+func (f *diskFilter) report(disk internal.Disk) bool {
+	dev := disk.Target.Dev
+	if f.deviceInclude != nil && !f.deviceInclude.MatchString(dev) {
+		return false
+	}
+	if f.deviceExclude != nil && f.deviceExclude.MatchString(dev) {
+		return false
+	}
+	return f.deviceTypes[disk.Device]
+}
+
+// reportName looks up a disk of the block stats by its target device.
+func (f *diskFilter) reportName(dom *domainMeta, dev string) bool {
+	disk, ok := dom.xml.Disk(dev)
+	return ok && f.report(disk)
+}
+
+// blockLabels returns the values of diskLabelNames for a block stats entry,
+// followed by extra. libvirt sets a backing index on the top layer too when
+// the backing chain is requested, it's left out so the series of the top
+// layer don't depend on the stats flags.
+func blockLabels(dom *domainMeta, v libvirt.DomainStatsBlock, top bool, extra ...string) []string {
+	backingIndex := ""
+	if !top && v.BackingIndexSet {
+		backingIndex = strconv.FormatUint(uint64(v.BackingIndex), 10)
+	}
+	return dom.labels(append([]string{v.Name, v.Path, backingIndex}, extra...)...)
+}
+
 type diskCollector struct {
 	readRequests  *prometheus.Desc
 	writeRequests *prometheus.Desc
+	flushRequests *prometheus.Desc
 	readBytes     *prometheus.Desc
 	writeBytes    *prometheus.Desc
+	readTime      *prometheus.Desc
+	writeTime     *prometheus.Desc
+	flushTime     *prometheus.Desc
+	errors        *prometheus.Desc
+	allocation    *prometheus.Desc
+	capacity      *prometheus.Desc
+	physical      *prometheus.Desc
 	availBytes    *prometheus.Desc
 	sizeBytes     *prometheus.Desc
 	inodes        *prometheus.Desc
 	availInodes   *prometheus.Desc
+	info          *prometheus.Desc
+
+	backingChainDepth *prometheus.Desc
+
+	filter *diskFilter
+
+	// v1 names, see --compat.v1-metrics
+	v1ReadRequests  *prometheus.Desc
+	v1WriteRequests *prometheus.Desc
+	v1ReadBytes     *prometheus.Desc
+	v1WriteBytes    *prometheus.Desc
 }
 
 func init() {
@@ -30,71 +121,182 @@ func init() {
 func newDiskCollector() (Collector, error) {
 	c := &diskCollector{
 		readRequests: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "read_requests"),
-			"",
-			[]string{"uuid", "target_device"}, nil),
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "read_requests_total"),
+			"Read requests completed by the disk.",
+			domainLabelNames(diskLabelNames...), nil),
 		writeRequests: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "write_requests"),
-			"",
-			[]string{"uuid", "target_device"}, nil),
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "write_requests_total"),
+			"Write requests completed by the disk.",
+			domainLabelNames(diskLabelNames...), nil),
+		flushRequests: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "flush_requests_total"),
+			"Flush requests completed by the disk.",
+			domainLabelNames(diskLabelNames...), nil),
 		readBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "read_bytes"),
-			"",
-			[]string{"uuid", "target_device"}, nil),
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "read_bytes_total"),
+			"Bytes read from the disk.",
+			domainLabelNames(diskLabelNames...), nil),
 		writeBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "write_bytes"),
-			"",
-			[]string{"uuid", "target_device"}, nil),
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "write_bytes_total"),
+			"Bytes written to the disk.",
+			domainLabelNames(diskLabelNames...), nil),
+		readTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "read_time_seconds_total"),
+			"Time spent on reads from the disk.",
+			domainLabelNames(diskLabelNames...), nil),
+		writeTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "write_time_seconds_total"),
+			"Time spent on writes to the disk.",
+			domainLabelNames(diskLabelNames...), nil),
+		flushTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "flush_time_seconds_total"),
+			"Time spent on flushes of the disk.",
+			domainLabelNames(diskLabelNames...), nil),
+		errors: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "errors_total"),
+			"Errors of the disk, only reported by some hypervisors.",
+			domainLabelNames(diskLabelNames...), nil),
+		allocation: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "allocation_bytes"),
+			"Highest offset written to the disk image in bytes, the space used on a block device.",
+			domainLabelNames(diskLabelNames...), nil),
+		capacity: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "capacity_bytes"),
+			"Size of the disk as seen by the guest in bytes.",
+			domainLabelNames(diskLabelNames...), nil),
+		physical: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "physical_bytes"),
+			"Size of the disk image on the host in bytes.",
+			domainLabelNames(diskLabelNames...), nil),
 
 		sizeBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "size_bytes"),
-			"",
-			[]string{"uuid", "target_device", "fstype", "mountpoint"}, nil),
+			"Size of the guest filesystem in bytes.",
+			domainLabelNames("target_device", "fstype", "mountpoint"), nil),
 		availBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "avail_bytes"),
-			"",
-			[]string{"uuid", "target_device", "fstype", "mountpoint"}, nil),
+			"Space available to unprivileged users on the guest filesystem in bytes.",
+			domainLabelNames("target_device", "fstype", "mountpoint"), nil),
 
 		inodes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "inodes"),
-			"",
-			[]string{"uuid", "target_device", "fstype", "mountpoint"}, nil),
+			"Number of inodes of the guest filesystem.",
+			domainLabelNames("target_device", "fstype", "mountpoint"), nil),
 		availInodes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "avail_inodes"),
-			"",
-			[]string{"uuid", "target_device", "fstype", "mountpoint"}, nil),
+			"Free inodes of the guest filesystem.",
+			domainLabelNames("target_device", "fstype", "mountpoint"), nil),
+
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "info"),
+			"Disk configuration from the domain XML, always 1. source is the file or block device, pool and image name the network or pool volume source.",
+			domainLabelNames("target_device", "device", "bus", "source_type", "source", "protocol", "pool", "image",
+				"driver_type", "cache", "serial", "read_only"), nil),
+		backingChainDepth: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "backing_chain_depth"),
+			"Number of backing images below the top image of the disk.",
+			domainLabelNames("target_device"), nil),
+
+		v1ReadRequests:  v1Desc(diskCollectorSubsystem, "read_requests", domainLabelNames("target_device")),
+		v1WriteRequests: v1Desc(diskCollectorSubsystem, "write_requests", domainLabelNames("target_device")),
+		v1ReadBytes:     v1Desc(diskCollectorSubsystem, "read_bytes", domainLabelNames("target_device")),
+		v1WriteBytes:    v1Desc(diskCollectorSubsystem, "write_bytes", domainLabelNames("target_device")),
 	}
+	c.filter = newDiskFilter()
 
 	return c, nil
 }
 
-func (c *diskCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, uuid string, rs rpcSet) error {
-	diskStats := stats.Block
-	for _, v := range diskStats {
-		if v.Name != "hda" {
-			ch <- prometheus.MustNewConstMetric(c.readRequests,
+func (c *diskCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
+	if dom.xmlErr != nil {
+		return dom.xmlErr
+	}
+	for _, disk := range dom.xml.Devices.Disks {
+		if !c.filter.report(disk) {
+			continue
+		}
+		pool, image := disk.SourcePool()
+		ch <- prometheus.MustNewConstMetric(c.info,
+			prometheus.GaugeValue,
+			1,
+			dom.labels(disk.Target.Dev,
+				disk.Device,
+				disk.Target.Bus,
+				disk.Type,
+				disk.SourcePath(),
+				disk.Source.Protocol,
+				pool,
+				image,
+				disk.Driver.Type,
+				disk.Driver.Cache,
+				disk.Serial,
+				strconv.FormatBool(disk.ReadOnly != nil))...)
+	}
+
+	// the top layer of a disk comes before its backing images, which are
+	// reported with CONNECT_GET_ALL_DOMAINS_STATS_BACKING
+	var disks []string
+	layers := map[string]int{}
+	for _, v := range stats.Block {
+		if !c.filter.reportName(dom, v.Name) {
+			continue
+		}
+		labels := blockLabels(dom, v, layers[v.Name] == 0)
+		// times are in nanoseconds
+		for _, m := range []struct {
+			desc      *prometheus.Desc
+			valueType prometheus.ValueType
+			set       bool
+			value     float64
+		}{
+			{c.readRequests, prometheus.CounterValue, v.RdReqsSet, float64(v.RdReqs)},
+			{c.writeRequests, prometheus.CounterValue, v.WrReqsSet, float64(v.WrReqs)},
+			{c.flushRequests, prometheus.CounterValue, v.FlReqsSet, float64(v.FlReqs)},
+			{c.readBytes, prometheus.CounterValue, v.RdBytesSet, float64(v.RdBytes)},
+			{c.writeBytes, prometheus.CounterValue, v.WrBytesSet, float64(v.WrBytes)},
+			{c.readTime, prometheus.CounterValue, v.RdTimesSet, float64(v.RdTimes) / 1e9},
+			{c.writeTime, prometheus.CounterValue, v.WrTimesSet, float64(v.WrTimes) / 1e9},
+			{c.flushTime, prometheus.CounterValue, v.FlTimesSet, float64(v.FlTimes) / 1e9},
+			{c.errors, prometheus.CounterValue, v.ErrorsSet, float64(v.Errors)},
+			{c.allocation, prometheus.GaugeValue, v.AllocationSet, float64(v.Allocation)},
+			{c.capacity, prometheus.GaugeValue, v.CapacitySet, float64(v.Capacity)},
+			{c.physical, prometheus.GaugeValue, v.PhysicalSet, float64(v.Physical)},
+		} {
+			if m.set {
+				ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, m.value, labels...)
+			}
+		}
+
+		if *compatV1Metrics && layers[v.Name] == 0 {
+			ch <- prometheus.MustNewConstMetric(c.v1ReadRequests,
 				prometheus.GaugeValue,
 				float64(v.RdReqs),
-				uuid,
-				v.Name)
-			ch <- prometheus.MustNewConstMetric(c.writeRequests,
+				dom.labels(v.Name)...)
+			ch <- prometheus.MustNewConstMetric(c.v1WriteRequests,
 				prometheus.GaugeValue,
 				float64(v.WrReqs),
-				uuid,
-				v.Name)
-			ch <- prometheus.MustNewConstMetric(c.readBytes,
+				dom.labels(v.Name)...)
+			ch <- prometheus.MustNewConstMetric(c.v1ReadBytes,
 				prometheus.GaugeValue,
 				float64(v.RdBytes),
-				uuid,
-				v.Name)
-			ch <- prometheus.MustNewConstMetric(c.writeBytes,
+				dom.labels(v.Name)...)
+			ch <- prometheus.MustNewConstMetric(c.v1WriteBytes,
 				prometheus.GaugeValue,
 				float64(v.WrBytes),
-				uuid,
-				v.Name)
+				dom.labels(v.Name)...)
 		}
+		if layers[v.Name] == 0 {
+			disks = append(disks, v.Name)
+		}
+		layers[v.Name]++
+	}
+	for _, name := range disks {
+		ch <- prometheus.MustNewConstMetric(c.backingChainDepth,
+			prometheus.GaugeValue,
+			float64(layers[name]-1),
+			dom.labels(name)...)
 	}
-	if rs.GuestExec {
+	if dom.rs.GuestExec {
 		execArg := qga.GuestExecArg{
 			Path: "/usr/bin/df",
 			Arg: []string{
@@ -122,31 +324,19 @@ func (c *diskCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.Domai
 			ch <- prometheus.MustNewConstMetric(c.sizeBytes,
 				prometheus.GaugeValue,
 				float64(s.Size),
-				uuid,
-				s.Labels.Device,
-				s.Labels.FsType,
-				s.Labels.MountPoint)
+				dom.labels(s.Labels.Device, s.Labels.FsType, s.Labels.MountPoint)...)
 			ch <- prometheus.MustNewConstMetric(c.availBytes,
 				prometheus.GaugeValue,
 				float64(s.Avail),
-				uuid,
-				s.Labels.Device,
-				s.Labels.FsType,
-				s.Labels.MountPoint)
+				dom.labels(s.Labels.Device, s.Labels.FsType, s.Labels.MountPoint)...)
 			ch <- prometheus.MustNewConstMetric(c.inodes,
 				prometheus.GaugeValue,
 				float64(s.Inodes),
-				uuid,
-				s.Labels.Device,
-				s.Labels.FsType,
-				s.Labels.MountPoint)
+				dom.labels(s.Labels.Device, s.Labels.FsType, s.Labels.MountPoint)...)
 			ch <- prometheus.MustNewConstMetric(c.availInodes,
 				prometheus.GaugeValue,
 				float64(s.IAvail),
-				uuid,
-				s.Labels.Device,
-				s.Labels.FsType,
-				s.Labels.MountPoint)
+				dom.labels(s.Labels.Device, s.Labels.FsType, s.Labels.MountPoint)...)
 		}
 	}
 	return nil
@@ -0,0 +1,167 @@
+package collector
+
+import (
+	"strconv"
+
+	"github.com/libvirt/libvirt-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	domainCollectorSubsystem = "domain"
+)
+
+var (
+	domainStates = map[libvirt.DomainState]string{
+		libvirt.DOMAIN_NOSTATE:     "nostate",
+		libvirt.DOMAIN_RUNNING:     "running",
+		libvirt.DOMAIN_BLOCKED:     "blocked",
+		libvirt.DOMAIN_PAUSED:      "paused",
+		libvirt.DOMAIN_SHUTDOWN:    "shutdown",
+		libvirt.DOMAIN_SHUTOFF:     "shutoff",
+		libvirt.DOMAIN_CRASHED:     "crashed",
+		libvirt.DOMAIN_PMSUSPENDED: "pmsuspended",
+	}
+
+	// reasons are numbered per state
+	domainStateReasons = map[libvirt.DomainState]map[int]string{
+		libvirt.DOMAIN_RUNNING: {
+			int(libvirt.DOMAIN_RUNNING_UNKNOWN):            "unknown",
+			int(libvirt.DOMAIN_RUNNING_BOOTED):             "booted",
+			int(libvirt.DOMAIN_RUNNING_MIGRATED):           "migrated",
+			int(libvirt.DOMAIN_RUNNING_RESTORED):           "restored",
+			int(libvirt.DOMAIN_RUNNING_FROM_SNAPSHOT):      "from_snapshot",
+			int(libvirt.DOMAIN_RUNNING_UNPAUSED):           "unpaused",
+			int(libvirt.DOMAIN_RUNNING_MIGRATION_CANCELED): "migration_canceled",
+			int(libvirt.DOMAIN_RUNNING_SAVE_CANCELED):      "save_canceled",
+			int(libvirt.DOMAIN_RUNNING_WAKEUP):             "wakeup",
+			int(libvirt.DOMAIN_RUNNING_CRASHED):            "crashed",
+			int(libvirt.DOMAIN_RUNNING_POSTCOPY):           "postcopy",
+		},
+		libvirt.DOMAIN_PAUSED: {
+			int(libvirt.DOMAIN_PAUSED_UNKNOWN):         "unknown",
+			int(libvirt.DOMAIN_PAUSED_USER):            "user",
+			int(libvirt.DOMAIN_PAUSED_MIGRATION):       "migration",
+			int(libvirt.DOMAIN_PAUSED_SAVE):            "save",
+			int(libvirt.DOMAIN_PAUSED_DUMP):            "dump",
+			int(libvirt.DOMAIN_PAUSED_IOERROR):         "ioerror",
+			int(libvirt.DOMAIN_PAUSED_WATCHDOG):        "watchdog",
+			int(libvirt.DOMAIN_PAUSED_FROM_SNAPSHOT):   "from_snapshot",
+			int(libvirt.DOMAIN_PAUSED_SHUTTING_DOWN):   "shutting_down",
+			int(libvirt.DOMAIN_PAUSED_SNAPSHOT):        "snapshot",
+			int(libvirt.DOMAIN_PAUSED_CRASHED):         "crashed",
+			int(libvirt.DOMAIN_PAUSED_STARTING_UP):     "starting_up",
+			int(libvirt.DOMAIN_PAUSED_POSTCOPY):        "postcopy",
+			int(libvirt.DOMAIN_PAUSED_POSTCOPY_FAILED): "postcopy_failed",
+		},
+		libvirt.DOMAIN_SHUTDOWN: {
+			int(libvirt.DOMAIN_SHUTDOWN_UNKNOWN): "unknown",
+			int(libvirt.DOMAIN_SHUTDOWN_USER):    "user",
+		},
+		libvirt.DOMAIN_SHUTOFF: {
+			int(libvirt.DOMAIN_SHUTOFF_UNKNOWN):       "unknown",
+			int(libvirt.DOMAIN_SHUTOFF_SHUTDOWN):      "shutdown",
+			int(libvirt.DOMAIN_SHUTOFF_DESTROYED):     "destroyed",
+			int(libvirt.DOMAIN_SHUTOFF_CRASHED):       "crashed",
+			int(libvirt.DOMAIN_SHUTOFF_MIGRATED):      "migrated",
+			int(libvirt.DOMAIN_SHUTOFF_SAVED):         "saved",
+			int(libvirt.DOMAIN_SHUTOFF_FAILED):        "failed",
+			int(libvirt.DOMAIN_SHUTOFF_FROM_SNAPSHOT): "from_snapshot",
+			int(libvirt.DOMAIN_SHUTOFF_DAEMON):        "daemon",
+		},
+		libvirt.DOMAIN_CRASHED: {
+			int(libvirt.DOMAIN_CRASHED_UNKNOWN):  "unknown",
+			int(libvirt.DOMAIN_CRASHED_PANICKED): "panicked",
+		},
+	}
+)
+
+type domainCollector struct {
+	state *prometheus.Desc
+	info  *prometheus.Desc
+}
+
+func init() {
+	registerCollector("domain", newDomainCollector)
+}
+
+func newDomainCollector() (Collector, error) {
+	c := &domainCollector{
+		state: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, domainCollectorSubsystem, "state"),
+			"Current state of the domain and the reason it is in this state, always 1.",
+			domainLabelNames("name", "state", "reason"), nil),
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, domainCollectorSubsystem, "info"),
+			"Domain attributes from the domain XML, always 1.",
+			append([]string{"uuid", "name", "title", "os_type", "machine", "emulator",
+				"vcpus", "max_vcpus", "memory_bytes", "max_memory_bytes",
+				"cpu_model", "autostart", "persistent"}, labelNames(infoLabels)...), nil),
+	}
+
+	return c, nil
+}
+
+func (c *domainCollector) CollectsInactive() {}
+
+func (c *domainCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
+	name, err := stats.Domain.GetName()
+	if err != nil {
+		return err
+	}
+
+	if stats.State != nil && stats.State.StateSet {
+		ch <- prometheus.MustNewConstMetric(c.state,
+			prometheus.GaugeValue,
+			1,
+			dom.labels(name,
+				domainStateName(stats.State.State),
+				domainStateReason(stats.State.State, stats.State.Reason))...)
+	}
+
+	if dom.xmlErr != nil {
+		return dom.xmlErr
+	}
+	autostart, err := stats.Domain.GetAutostart()
+	if err != nil {
+		return err
+	}
+	persistent, err := stats.Domain.IsPersistent()
+	if err != nil {
+		return err
+	}
+	infoValues := []string{
+		dom.uuid,
+		name,
+		dom.xml.Title,
+		dom.xml.OS.Type.Value,
+		dom.xml.OS.Type.Machine,
+		dom.xml.Devices.Emulator,
+		strconv.FormatUint(uint64(dom.xml.CurrentVCPUs()), 10),
+		strconv.FormatUint(uint64(dom.xml.VCPU.Value), 10),
+		strconv.FormatUint(dom.xml.CurrentMemory.Bytes(), 10),
+		strconv.FormatUint(dom.xml.Memory.Bytes(), 10),
+		dom.xml.CPUModel(),
+		strconv.FormatBool(autostart),
+		strconv.FormatBool(persistent),
+	}
+	ch <- prometheus.MustNewConstMetric(c.info,
+		prometheus.GaugeValue,
+		1,
+		append(infoValues, dom.infoLabels...)...)
+	return nil
+}
+
+func domainStateName(state libvirt.DomainState) string {
+	if name, ok := domainStates[state]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func domainStateReason(state libvirt.DomainState, reason int) string {
+	if name, ok := domainStateReasons[state][reason]; ok {
+		return name
+	}
+	return "unknown"
+}
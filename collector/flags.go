@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// secondsValue is a duration flag which only takes whole seconds from min to
+// max, for the durations libvirt takes in seconds. A max of 0 means no limit.
+type secondsValue struct {
+	d        *time.Duration
+	min, max time.Duration
+}
+
+func (v *secondsValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if d%time.Second != 0 {
+		return fmt.Errorf("%q is not a whole number of seconds", s)
+	}
+	if d < v.min {
+		return fmt.Errorf("%q is less than %s", s, v.min)
+	}
+	if v.max > 0 && d > v.max {
+		return fmt.Errorf("%q is more than %s", s, v.max)
+	}
+	*v.d = d
+	return nil
+}
+
+func (v *secondsValue) String() string {
+	return v.d.String()
+}
+
+func secondsFlag(f *kingpin.FlagClause, min, max time.Duration) *time.Duration {
+	d := new(time.Duration)
+	f.SetValue(&secondsValue{d, min, max})
+	return d
+}
+
+// regexpValue is a regexp flag which has to match whole strings, it is nil
+// if the flag isn't set.
+type regexpValue struct {
+	re *regexp.Regexp
+}
+
+func (v *regexpValue) Set(s string) error {
+	re, err := regexp.Compile("^(?:" + s + ")$")
+	if err != nil {
+		return err
+	}
+	v.re = re
+	return nil
+}
+
+func (v *regexpValue) String() string {
+	if v.re == nil {
+		return ""
+	}
+	return v.re.String()
+}
+
+func regexpFlag(f *kingpin.FlagClause) **regexp.Regexp {
+	v := &regexpValue{}
+	f.SetValue(v)
+	return &v.re
+}
@@ -0,0 +1,128 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/libvirt/libvirt-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	guestCollectorSubsystem = "guest"
+)
+
+type guestCollector struct {
+	bootTime        *prometheus.Desc
+	uptime          *prometheus.Desc
+	contextSwitches *prometheus.Desc
+	interrupts      *prometheus.Desc
+	forks           *prometheus.Desc
+	procsRunning    *prometheus.Desc
+	procsBlocked    *prometheus.Desc
+	softirqs        *prometheus.Desc
+}
+
+func init() {
+	registerCollector("guest", newGuestCollector)
+}
+
+func newGuestCollector() (Collector, error) {
+	c := &guestCollector{
+		bootTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, guestCollectorSubsystem, "boot_time_seconds"),
+			"Guest boot time, in unixtime.",
+			domainLabelNames(), nil),
+		uptime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, guestCollectorSubsystem, "uptime_seconds"),
+			"Seconds since the guest booted, measured with the host clock.",
+			domainLabelNames(), nil),
+		contextSwitches: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, guestCollectorSubsystem, "context_switches_total"),
+			"Total number of context switches in the guest.",
+			domainLabelNames(), nil),
+		interrupts: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, guestCollectorSubsystem, "interrupts_total"),
+			"Total number of interrupts serviced in the guest.",
+			domainLabelNames(), nil),
+		forks: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, guestCollectorSubsystem, "forks_total"),
+			"Total number of forks in the guest.",
+			domainLabelNames(), nil),
+		procsRunning: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, guestCollectorSubsystem, "procs_running"),
+			"Number of processes in runnable state in the guest.",
+			domainLabelNames(), nil),
+		procsBlocked: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, guestCollectorSubsystem, "procs_blocked"),
+			"Number of processes blocked waiting for I/O in the guest.",
+			domainLabelNames(), nil),
+		softirqs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, guestCollectorSubsystem, "softirqs_total"),
+			"Number of softirqs in the guest, by type.",
+			domainLabelNames("type"), nil),
+	}
+
+	return c, nil
+}
+
+func (c *guestCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
+	if !dom.rs.GuestFileRead {
+		return nil
+	}
+	s, err := dom.guestStat(stats.Domain)
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.bootTime,
+		prometheus.GaugeValue,
+		float64(s.BootTime),
+		dom.labels()...)
+	ch <- prometheus.MustNewConstMetric(c.uptime,
+		prometheus.GaugeValue,
+		time.Since(time.Unix(int64(s.BootTime), 0)).Seconds(),
+		dom.labels()...)
+	ch <- prometheus.MustNewConstMetric(c.contextSwitches,
+		prometheus.CounterValue,
+		float64(s.ContextSwitches),
+		dom.labels()...)
+	ch <- prometheus.MustNewConstMetric(c.interrupts,
+		prometheus.CounterValue,
+		float64(s.IRQTotal),
+		dom.labels()...)
+	ch <- prometheus.MustNewConstMetric(c.forks,
+		prometheus.CounterValue,
+		float64(s.ProcessCreated),
+		dom.labels()...)
+	ch <- prometheus.MustNewConstMetric(c.procsRunning,
+		prometheus.GaugeValue,
+		float64(s.ProcessesRunning),
+		dom.labels()...)
+	ch <- prometheus.MustNewConstMetric(c.procsBlocked,
+		prometheus.GaugeValue,
+		float64(s.ProcessesBlocked),
+		dom.labels()...)
+
+	softirqs := []struct {
+		name  string
+		value uint64
+	}{
+		{"hi", s.SoftIRQ.Hi},
+		{"timer", s.SoftIRQ.Timer},
+		{"net_tx", s.SoftIRQ.NetTx},
+		{"net_rx", s.SoftIRQ.NetRx},
+		{"block", s.SoftIRQ.Block},
+		{"block_iopoll", s.SoftIRQ.BlockIoPoll},
+		{"tasklet", s.SoftIRQ.Tasklet},
+		{"sched", s.SoftIRQ.Sched},
+		{"hrtimer", s.SoftIRQ.Hrtimer},
+		{"rcu", s.SoftIRQ.Rcu},
+	}
+	for _, si := range softirqs {
+		ch <- prometheus.MustNewConstMetric(c.softirqs,
+			prometheus.CounterValue,
+			float64(si.value),
+			dom.labels(si.name)...)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package collector
+
+import (
+	"prometheus_libvirt_exporter/collector/qga"
+	"prometheus_libvirt_exporter/internal"
+
+	"github.com/libvirt/libvirt-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type guestMemCollector struct {
+	memTotal       *prometheus.Desc
+	memUsed        *prometheus.Desc
+	memAvailable   *prometheus.Desc
+	memFree        *prometheus.Desc
+	buffers        *prometheus.Desc
+	cached         *prometheus.Desc
+	slab           *prometheus.Desc
+	dirty          *prometheus.Desc
+	swapTotal      *prometheus.Desc
+	swapFree       *prometheus.Desc
+	committedAS    *prometheus.Desc
+	hugePagesTotal *prometheus.Desc
+	hugePagesFree  *prometheus.Desc
+	hugePagesRsvd  *prometheus.Desc
+	hugePagesSurp  *prometheus.Desc
+	hugepageSize   *prometheus.Desc
+}
+
+func init() {
+	registerCollector("guestmem", newGuestMemCollector)
+}
+
+func newGuestMemCollector() (Collector, error) {
+	c := &guestMemCollector{
+		memTotal:     newMemTotalDesc(),
+		memUsed:      newMemUsedDesc(),
+		memAvailable: newMemAvailableDesc(),
+		memFree: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "free_bytes"),
+			"Memory left unused by the guest in bytes, MemFree in /proc/meminfo.",
+			domainLabelNames(), nil),
+		buffers: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "buffers_bytes"),
+			"Memory used by the guest for block device buffers in bytes.",
+			domainLabelNames(), nil),
+		cached: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "cached_bytes"),
+			"Memory used by the guest for the page cache in bytes.",
+			domainLabelNames(), nil),
+		slab: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "slab_bytes"),
+			"Memory used by the guest kernel slab allocator in bytes.",
+			domainLabelNames(), nil),
+		dirty: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "dirty_bytes"),
+			"Memory in the guest waiting to be written back to disk in bytes.",
+			domainLabelNames(), nil),
+		swapTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "swap_total_bytes"),
+			"Swap space of the guest in bytes.",
+			domainLabelNames(), nil),
+		swapFree: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "swap_free_bytes"),
+			"Unused swap space of the guest in bytes.",
+			domainLabelNames(), nil),
+		committedAS: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "committed_as_bytes"),
+			"Memory allocated by the guest processes, even if not used yet, in bytes.",
+			domainLabelNames(), nil),
+		hugePagesTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "hugepages"),
+			"Size of the huge page pool of the guest in pages.",
+			domainLabelNames(), nil),
+		hugePagesFree: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "hugepages_free"),
+			"Huge pages of the guest not allocated yet.",
+			domainLabelNames(), nil),
+		hugePagesRsvd: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "hugepages_reserved"),
+			"Huge pages of the guest reserved for an allocation, but not allocated yet.",
+			domainLabelNames(), nil),
+		hugePagesSurp: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "hugepages_surplus"),
+			"Huge pages of the guest above the size of the pool.",
+			domainLabelNames(), nil),
+		hugepageSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "hugepage_size_bytes"),
+			"Default huge page size of the guest in bytes.",
+			domainLabelNames(), nil),
+	}
+
+	return c, nil
+}
+
+func (c *guestMemCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
+	if !dom.rs.GuestFileRead {
+		return nil
+	}
+	data, err := qga.ReadFile(stats.Domain, "/proc/meminfo")
+	if err != nil {
+		return err
+	}
+	m, err := internal.GetMeminfo(data)
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.memTotal,
+		prometheus.GaugeValue,
+		float64(m.MemTotal),
+		dom.labels("guest")...)
+	ch <- prometheus.MustNewConstMetric(c.memUsed,
+		prometheus.GaugeValue,
+		float64(m.MemTotal-m.MemFree),
+		dom.labels("guest")...)
+	if m.MemAvailableSet {
+		ch <- prometheus.MustNewConstMetric(c.memAvailable,
+			prometheus.GaugeValue,
+			float64(m.MemAvailable),
+			dom.labels("guest")...)
+	}
+	for desc, v := range map[*prometheus.Desc]uint64{
+		c.memFree:        m.MemFree,
+		c.buffers:        m.Buffers,
+		c.cached:         m.Cached,
+		c.slab:           m.Slab,
+		c.dirty:          m.Dirty,
+		c.swapTotal:      m.SwapTotal,
+		c.swapFree:       m.SwapFree,
+		c.committedAS:    m.CommittedAS,
+		c.hugePagesTotal: m.HugePagesTotal,
+		c.hugePagesFree:  m.HugePagesFree,
+		c.hugePagesRsvd:  m.HugePagesRsvd,
+		c.hugePagesSurp:  m.HugePagesSurp,
+		c.hugepageSize:   m.Hugepagesize,
+	} {
+		ch <- prometheus.MustNewConstMetric(desc,
+			prometheus.GaugeValue,
+			float64(v),
+			dom.labels()...)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package collector
+
+import (
+	"github.com/libvirt/libvirt-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type iotuneCollector struct {
+	totalBytesSec          *prometheus.Desc
+	readBytesSec           *prometheus.Desc
+	writeBytesSec          *prometheus.Desc
+	totalIopsSec           *prometheus.Desc
+	readIopsSec            *prometheus.Desc
+	writeIopsSec           *prometheus.Desc
+	totalBytesSecMax       *prometheus.Desc
+	readBytesSecMax        *prometheus.Desc
+	writeBytesSecMax       *prometheus.Desc
+	totalIopsSecMax        *prometheus.Desc
+	readIopsSecMax         *prometheus.Desc
+	writeIopsSecMax        *prometheus.Desc
+	totalBytesSecMaxLength *prometheus.Desc
+	readBytesSecMaxLength  *prometheus.Desc
+	writeBytesSecMaxLength *prometheus.Desc
+	totalIopsSecMaxLength  *prometheus.Desc
+	readIopsSecMaxLength   *prometheus.Desc
+	writeIopsSecMaxLength  *prometheus.Desc
+	sizeIopsSec            *prometheus.Desc
+	groupInfo              *prometheus.Desc
+
+	filter *diskFilter
+}
+
+func init() {
+	registerCollector("iotune", newIOTuneCollector)
+}
+
+func newIOTuneCollector() (Collector, error) {
+	c := &iotuneCollector{
+		totalBytesSec: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_total_bytes_per_second"),
+			"Total bytes per second limit of the disk, reads and writes combined.",
+			domainLabelNames(diskLabelNames...), nil),
+		readBytesSec: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_read_bytes_per_second"),
+			"Read bytes per second limit of the disk.",
+			domainLabelNames(diskLabelNames...), nil),
+		writeBytesSec: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_write_bytes_per_second"),
+			"Write bytes per second limit of the disk.",
+			domainLabelNames(diskLabelNames...), nil),
+		totalIopsSec: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_total_iops"),
+			"Total I/O operations per second limit of the disk.",
+			domainLabelNames(diskLabelNames...), nil),
+		readIopsSec: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_read_iops"),
+			"Read I/O operations per second limit of the disk.",
+			domainLabelNames(diskLabelNames...), nil),
+		writeIopsSec: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_write_iops"),
+			"Write I/O operations per second limit of the disk.",
+			domainLabelNames(diskLabelNames...), nil),
+		totalBytesSecMax: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_total_bytes_per_second_burst"),
+			"Total bytes per second burst limit of the disk.",
+			domainLabelNames(diskLabelNames...), nil),
+		readBytesSecMax: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_read_bytes_per_second_burst"),
+			"Read bytes per second burst limit of the disk.",
+			domainLabelNames(diskLabelNames...), nil),
+		writeBytesSecMax: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_write_bytes_per_second_burst"),
+			"Write bytes per second burst limit of the disk.",
+			domainLabelNames(diskLabelNames...), nil),
+		totalIopsSecMax: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_total_iops_burst"),
+			"Total I/O operations per second burst limit of the disk.",
+			domainLabelNames(diskLabelNames...), nil),
+		readIopsSecMax: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_read_iops_burst"),
+			"Read I/O operations per second burst limit of the disk.",
+			domainLabelNames(diskLabelNames...), nil),
+		writeIopsSecMax: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_write_iops_burst"),
+			"Write I/O operations per second burst limit of the disk.",
+			domainLabelNames(diskLabelNames...), nil),
+		totalBytesSecMaxLength: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_total_bytes_per_second_burst_length_seconds"),
+			"Time the total bytes per second burst limit may be used.",
+			domainLabelNames(diskLabelNames...), nil),
+		readBytesSecMaxLength: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_read_bytes_per_second_burst_length_seconds"),
+			"Time the read bytes per second burst limit may be used.",
+			domainLabelNames(diskLabelNames...), nil),
+		writeBytesSecMaxLength: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_write_bytes_per_second_burst_length_seconds"),
+			"Time the write bytes per second burst limit may be used.",
+			domainLabelNames(diskLabelNames...), nil),
+		totalIopsSecMaxLength: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_total_iops_burst_length_seconds"),
+			"Time the total I/O operations per second burst limit may be used.",
+			domainLabelNames(diskLabelNames...), nil),
+		readIopsSecMaxLength: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_read_iops_burst_length_seconds"),
+			"Time the read I/O operations per second burst limit may be used.",
+			domainLabelNames(diskLabelNames...), nil),
+		writeIopsSecMaxLength: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_write_iops_burst_length_seconds"),
+			"Time the write I/O operations per second burst limit may be used.",
+			domainLabelNames(diskLabelNames...), nil),
+		sizeIopsSec: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_size_iops_bytes"),
+			"Size of an I/O operation counted by the IOPS limits, larger ones count as several.",
+			domainLabelNames(diskLabelNames...), nil),
+		groupInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskCollectorSubsystem, "iotune_group_info"),
+			"Throttle group sharing the limits of the disk, always 1.",
+			domainLabelNames("target_device", "source", "backing_index", "group"), nil),
+	}
+	c.filter = newDiskFilter()
+
+	return c, nil
+}
+
+func (c *iotuneCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
+	if dom.xmlErr != nil {
+		return dom.xmlErr
+	}
+	// limits apply to the top layer of a disk, which comes first
+	seen := map[string]bool{}
+	for _, v := range stats.Block {
+		if seen[v.Name] || !c.filter.reportName(dom, v.Name) {
+			continue
+		}
+		seen[v.Name] = true
+
+		p, err := stats.Domain.GetBlockIoTune(v.Name, libvirt.DOMAIN_AFFECT_LIVE)
+		if isUnsupported(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		labels := blockLabels(dom, v, true)
+		// 0 means no limit
+		for _, m := range []struct {
+			desc  *prometheus.Desc
+			set   bool
+			value uint64
+		}{
+			{c.totalBytesSec, p.TotalBytesSecSet, p.TotalBytesSec},
+			{c.readBytesSec, p.ReadBytesSecSet, p.ReadBytesSec},
+			{c.writeBytesSec, p.WriteBytesSecSet, p.WriteBytesSec},
+			{c.totalIopsSec, p.TotalIopsSecSet, p.TotalIopsSec},
+			{c.readIopsSec, p.ReadIopsSecSet, p.ReadIopsSec},
+			{c.writeIopsSec, p.WriteIopsSecSet, p.WriteIopsSec},
+			{c.totalBytesSecMax, p.TotalBytesSecMaxSet, p.TotalBytesSecMax},
+			{c.readBytesSecMax, p.ReadBytesSecMaxSet, p.ReadBytesSecMax},
+			{c.writeBytesSecMax, p.WriteBytesSecMaxSet, p.WriteBytesSecMax},
+			{c.totalIopsSecMax, p.TotalIopsSecMaxSet, p.TotalIopsSecMax},
+			{c.readIopsSecMax, p.ReadIopsSecMaxSet, p.ReadIopsSecMax},
+			{c.writeIopsSecMax, p.WriteIopsSecMaxSet, p.WriteIopsSecMax},
+			{c.totalBytesSecMaxLength, p.TotalBytesSecMaxLengthSet, p.TotalBytesSecMaxLength},
+			{c.readBytesSecMaxLength, p.ReadBytesSecMaxLengthSet, p.ReadBytesSecMaxLength},
+			{c.writeBytesSecMaxLength, p.WriteBytesSecMaxLengthSet, p.WriteBytesSecMaxLength},
+			{c.totalIopsSecMaxLength, p.TotalIopsSecMaxLengthSet, p.TotalIopsSecMaxLength},
+			{c.readIopsSecMaxLength, p.ReadIopsSecMaxLengthSet, p.ReadIopsSecMaxLength},
+			{c.writeIopsSecMaxLength, p.WriteIopsSecMaxLengthSet, p.WriteIopsSecMaxLength},
+			{c.sizeIopsSec, p.SizeIopsSecSet, p.SizeIopsSec},
+		} {
+			if m.set && m.value > 0 {
+				ch <- prometheus.MustNewConstMetric(m.desc, prometheus.GaugeValue, float64(m.value), labels...)
+			}
+		}
+		if p.GroupNameSet && p.GroupName != "" {
+			ch <- prometheus.MustNewConstMetric(c.groupInfo,
+				prometheus.GaugeValue,
+				1,
+				blockLabels(dom, v, true, p.GroupName)...)
+		}
+	}
+	return nil
+}
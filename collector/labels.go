@@ -0,0 +1,148 @@
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"prometheus_libvirt_exporter/internal"
+	"regexp"
+	"sync"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	domainLabelsConfig = kingpin.Flag(
+		"collector.domain.labels-config",
+		"YAML file mapping values of the domain XML to extra labels.",
+	).String()
+
+	labelNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+	// label names already used by the collectors
+	reservedLabels = map[string]bool{
+		"uuid": true, "name": true, "domain": true, "collector": true,
+		"state": true, "reason": true, "title": true, "os_type": true,
+		"machine": true, "emulator": true, "vcpus": true, "max_vcpus": true,
+		"memory_bytes": true, "max_memory_bytes": true, "cpu_model": true,
+		"autostart": true, "persistent": true,
+		"instance_name": true, "flavor": true, "project_id": true,
+		"project_name": true, "user_id": true, "user_name": true,
+		"cpu": true, "vcpu": true, "cpuset": true, "iothread": true, "mode": true, "nodeset": true,
+		"type": true, "target_device": true, "fstype": true, "mountpoint": true,
+		"source": true, "resource": true, "window": true,
+		"status": true, "backing_index": true,
+		"device": true, "bus": true, "source_type": true, "protocol": true, "pool": true,
+		"image": true, "driver_type": true, "cache": true, "serial": true, "read_only": true,
+		"group": true, "snapshot": true,
+	}
+
+	loadLabelsOnce sync.Once
+	loadLabelsErr  error
+
+	// all configured labels, put on libvirt_domain_info
+	infoLabels []domainLabel
+	// the allow-listed labels which are put on every series of a domain
+	metricLabels []domainLabel
+)
+
+type labelsConfig struct {
+	// prefixes used in xpath
+	Namespaces map[string]string `yaml:"namespaces"`
+	Labels     []struct {
+		Name string `yaml:"name"`
+		// either an xpath, or a key below the metadata element of namespace
+		XPath     string `yaml:"xpath"`
+		Namespace string `yaml:"namespace"`
+		Key       string `yaml:"key"`
+	} `yaml:"labels"`
+	// allow-list of labels which also go on the cpu, mem, disk and network series
+	MetricLabels []string `yaml:"metric_labels"`
+}
+
+type domainLabel struct {
+	name string
+	path internal.XMLPath
+}
+
+// loadDomainLabels reads --collector.domain.labels-config once.
+func loadDomainLabels() error {
+	loadLabelsOnce.Do(func() {
+		if *domainLabelsConfig == "" {
+			return
+		}
+		infoLabels, metricLabels, loadLabelsErr = parseLabelsConfig(*domainLabelsConfig)
+	})
+	return loadLabelsErr
+}
+
+func parseLabelsConfig(file string) ([]domainLabel, []domainLabel, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg := labelsConfig{}
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, nil, fmt.Errorf("couldn't parse %s: %w", file, err)
+	}
+
+	all := []domainLabel{}
+	byName := map[string]domainLabel{}
+	for _, l := range cfg.Labels {
+		if !labelNameRE.MatchString(l.Name) || reservedLabels[l.Name] {
+			return nil, nil, fmt.Errorf("invalid label name %q", l.Name)
+		}
+		if _, exist := byName[l.Name]; exist {
+			return nil, nil, fmt.Errorf("duplicate label %q", l.Name)
+		}
+		var path internal.XMLPath
+		switch {
+		case l.XPath != "" && l.Namespace == "":
+			path, err = internal.ParseXMLPath(l.XPath, cfg.Namespaces)
+			if err != nil {
+				return nil, nil, fmt.Errorf("label %q: %w", l.Name, err)
+			}
+		case l.XPath == "" && l.Namespace != "":
+			path = internal.MetadataPath(l.Namespace, l.Key)
+		default:
+			return nil, nil, fmt.Errorf("label %q: exactly one of xpath and namespace is required", l.Name)
+		}
+		byName[l.Name] = domainLabel{l.Name, path}
+		all = append(all, byName[l.Name])
+	}
+
+	allowed := []domainLabel{}
+	for _, name := range cfg.MetricLabels {
+		l, exist := byName[name]
+		if !exist {
+			return nil, nil, fmt.Errorf("metric label %q is not configured", name)
+		}
+		allowed = append(allowed, l)
+	}
+	return all, allowed, nil
+}
+
+func labelNames(labels []domainLabel) []string {
+	names := make([]string, 0, len(labels))
+	for _, l := range labels {
+		names = append(names, l.name)
+	}
+	return names
+}
+
+// labelValues looks up the labels in the domain XML, missing values are empty.
+func labelValues(node internal.XMLNode, labels []domainLabel) []string {
+	values := make([]string, 0, len(labels))
+	for _, l := range labels {
+		value, _ := node.Lookup(l.path)
+		values = append(values, value)
+	}
+	return values
+}
+
+// domainLabelNames returns the label names of a per-domain series: the uuid,
+// the allow-listed metric labels and names.
+func domainLabelNames(names ...string) []string {
+	ret := append([]string{"uuid"}, labelNames(metricLabels)...)
+	return append(ret, names...)
+}
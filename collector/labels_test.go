@@ -0,0 +1,145 @@
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseLabelsConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		info    []string
+		metric  []string
+		wantErr bool
+	}{
+		{
+			name: "xpath and metadata key",
+			config: `
+namespaces:
+  app: http://example.org/app/1.0
+labels:
+  - name: owner
+    xpath: /domain/metadata/app:instance/app:owner/text()
+  - name: project
+    namespace: http://example.org/app/1.0
+    key: project/@id
+metric_labels:
+  - project
+`,
+			info:   []string{"owner", "project"},
+			metric: []string{"project"},
+		},
+		{
+			name:   "empty",
+			config: ``,
+			info:   []string{},
+			metric: []string{},
+		},
+		{
+			name: "reserved name",
+			config: `
+labels:
+  - name: uuid
+    xpath: /domain/uuid
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid name",
+			config: `
+labels:
+  - name: 1owner
+    xpath: /domain/name
+`,
+			wantErr: true,
+		},
+		{
+			name: "duplicate name",
+			config: `
+labels:
+  - name: owner
+    xpath: /domain/name
+  - name: owner
+    xpath: /domain/title
+`,
+			wantErr: true,
+		},
+		{
+			name: "unknown prefix",
+			config: `
+labels:
+  - name: owner
+    xpath: /domain/metadata/app:instance
+`,
+			wantErr: true,
+		},
+		{
+			name: "xpath and namespace",
+			config: `
+labels:
+  - name: owner
+    xpath: /domain/name
+    namespace: http://example.org/app/1.0
+`,
+			wantErr: true,
+		},
+		{
+			name: "neither xpath nor namespace",
+			config: `
+labels:
+  - name: owner
+`,
+			wantErr: true,
+		},
+		{
+			name: "metric label not configured",
+			config: `
+metric_labels:
+  - owner
+`,
+			wantErr: true,
+		},
+		{
+			name: "unknown field",
+			config: `
+label:
+  - name: owner
+`,
+			wantErr: true,
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "labels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i, test := range tests {
+		file := filepath.Join(dir, string(rune('a'+i))+".yml")
+		if err := ioutil.WriteFile(file, []byte(test.config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		info, metric, err := parseLabelsConfig(file)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got := labelNames(info); !reflect.DeepEqual(got, test.info) {
+			t.Errorf("%s: got labels %v, want %v", test.name, got, test.info)
+		}
+		if got := labelNames(metric); !reflect.DeepEqual(got, test.metric) {
+			t.Errorf("%s: got metric labels %v, want %v", test.name, got, test.metric)
+		}
+	}
+}
@@ -1,18 +1,63 @@
 package collector
 
 import (
+	"sync"
+	"time"
+
 	"github.com/libvirt/libvirt-go"
 	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 const (
-	memCollectorSubsystem = "mem"
+	memCollectorSubsystem     = "mem"
+	balloonCollectorSubsystem = "balloon"
+)
+
+var (
+	// libvirt takes the balloon stats period in seconds
+	memStatsPeriod = secondsFlag(kingpin.Flag(
+		"collector.mem.stats-period",
+		"Set this balloon stats period on running domains which have none, live only. Whole seconds, 0 disables it.",
+	).Default("0s"), 0, 0)
 )
 
+// domains the exporter set the stats period on are forgotten when they
+// weren't seen for this long, e.g. because they were undefined
+const periodSetTTL = time.Hour
+
 type memCollector struct {
 	memTotal     *prometheus.Desc
 	memUsed      *prometheus.Desc
 	memAvailable *prometheus.Desc
+
+	balloonCurrent        *prometheus.Desc
+	balloonMaximum        *prometheus.Desc
+	balloonSwapIn         *prometheus.Desc
+	balloonSwapOut        *prometheus.Desc
+	balloonMajorFault     *prometheus.Desc
+	balloonMinorFault     *prometheus.Desc
+	balloonUnused         *prometheus.Desc
+	balloonAvailable      *prometheus.Desc
+	balloonUsable         *prometheus.Desc
+	balloonRss            *prometheus.Desc
+	balloonDiskCaches     *prometheus.Desc
+	balloonHugetlbPgAlloc *prometheus.Desc
+	balloonHugetlbPgFail  *prometheus.Desc
+	balloonLastUpdate     *prometheus.Desc
+	balloonAge            *prometheus.Desc
+	statsPeriod           *prometheus.Desc
+	statsPeriodSet        *prometheus.Desc
+
+	// domains the stats period was set on by the exporter, to when they
+	// were last seen
+	mu        sync.Mutex
+	periodSet map[string]time.Time
+
+	// v1 names, see --compat.v1-metrics
+	v1MemTotal     *prometheus.Desc
+	v1MemUsed      *prometheus.Desc
+	v1MemAvailable *prometheus.Desc
 }
 
 func init() {
@@ -21,39 +66,236 @@ func init() {
 
 func newMemCollector() (Collector, error) {
 	c := &memCollector{
-		memTotal: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, memCollectorSubsystem, "total"),
-			"",
-			[]string{"uuid"}, nil),
-		memUsed: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, memCollectorSubsystem, "used"),
-			"used with buff/cache",
-			[]string{"uuid"}, nil),
-		memAvailable: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, memCollectorSubsystem, "available"),
-			"available without buff/cache",
-			[]string{"uuid"}, nil),
+		memTotal:     newMemTotalDesc(),
+		memUsed:      newMemUsedDesc(),
+		memAvailable: newMemAvailableDesc(),
+
+		balloonCurrent: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, balloonCollectorSubsystem, "current_bytes"),
+			"Current balloon target of the domain in bytes.",
+			domainLabelNames(), nil),
+		balloonMaximum: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, balloonCollectorSubsystem, "maximum_bytes"),
+			"Maximum memory the balloon can be grown to in bytes.",
+			domainLabelNames(), nil),
+		balloonSwapIn: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, balloonCollectorSubsystem, "swap_in_bytes_total"),
+			"Bytes swapped in by the guest.",
+			domainLabelNames(), nil),
+		balloonSwapOut: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, balloonCollectorSubsystem, "swap_out_bytes_total"),
+			"Bytes swapped out by the guest.",
+			domainLabelNames(), nil),
+		balloonMajorFault: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, balloonCollectorSubsystem, "major_faults_total"),
+			"Page faults in the guest which required disk IO.",
+			domainLabelNames(), nil),
+		balloonMinorFault: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, balloonCollectorSubsystem, "minor_faults_total"),
+			"Page faults in the guest which were served without disk IO.",
+			domainLabelNames(), nil),
+		balloonUnused: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, balloonCollectorSubsystem, "unused_bytes"),
+			"Memory left completely unused by the guest in bytes.",
+			domainLabelNames(), nil),
+		balloonAvailable: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, balloonCollectorSubsystem, "available_bytes"),
+			"Memory the guest sees as total in bytes.",
+			domainLabelNames(), nil),
+		balloonUsable: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, balloonCollectorSubsystem, "usable_bytes"),
+			"Memory the guest can reclaim without swapping in bytes.",
+			domainLabelNames(), nil),
+		balloonRss: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, balloonCollectorSubsystem, "rss_bytes"),
+			"Resident memory of the QEMU process on the host in bytes.",
+			domainLabelNames(), nil),
+		balloonDiskCaches: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, balloonCollectorSubsystem, "disk_caches_bytes"),
+			"Memory used by the guest for disk caches, which can be reclaimed, in bytes.",
+			domainLabelNames(), nil),
+		balloonHugetlbPgAlloc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, balloonCollectorSubsystem, "hugetlb_pgalloc_total"),
+			"Successful huge page allocations in the guest.",
+			domainLabelNames(), nil),
+		balloonHugetlbPgFail: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, balloonCollectorSubsystem, "hugetlb_pgfail_total"),
+			"Failed huge page allocations in the guest.",
+			domainLabelNames(), nil),
+		balloonLastUpdate: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, balloonCollectorSubsystem, "last_update_timestamp_seconds"),
+			"Time the guest balloon driver last reported its statistics.",
+			domainLabelNames(), nil),
+		balloonAge: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, balloonCollectorSubsystem, "stats_age_seconds"),
+			"Seconds since the guest balloon driver last reported its statistics, a growing value means the guest stopped reporting.",
+			domainLabelNames(), nil),
+		statsPeriod: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, balloonCollectorSubsystem, "stats_period_seconds"),
+			"Interval the guest balloon driver reports its statistics in, 0 if disabled.",
+			domainLabelNames(), nil),
+		statsPeriodSet: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, balloonCollectorSubsystem, "stats_period_set_by_exporter"),
+			"Whether the exporter set the balloon stats period of the domain, see --collector.mem.stats-period.",
+			domainLabelNames(), nil),
+		periodSet: map[string]time.Time{},
+
+		v1MemTotal:     v1Desc(memCollectorSubsystem, "total", domainLabelNames()),
+		v1MemUsed:      v1Desc(memCollectorSubsystem, "used", domainLabelNames()),
+		v1MemAvailable: v1Desc(memCollectorSubsystem, "available", domainLabelNames()),
 	}
 
 	return c, nil
 }
 
-func (c *memCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, uuid string, rs rpcSet) error {
+// The memory totals are reported by both the balloon driver and the guest
+// agent, source tells them apart.
+
+func newMemTotalDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, memCollectorSubsystem, "total_bytes"),
+		"Memory seen by the guest in bytes, the current balloon size if the balloon driver doesn't report it.",
+		domainLabelNames("source"), nil)
+}
+
+func newMemUsedDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, memCollectorSubsystem, "used_bytes"),
+		"Memory used by the guest in bytes, including buffers and caches.",
+		domainLabelNames("source"), nil)
+}
+
+func newMemAvailableDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, memCollectorSubsystem, "available_bytes"),
+		"Memory the guest can use without swapping in bytes.",
+		domainLabelNames("source"), nil)
+}
+
+func (c *memCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
+	// libvirt leaves out the balloon group e.g. for domains without a
+	// balloon device
+	if stats.Balloon == nil {
+		return nil
+	}
+	c.updateBalloon(ch, stats.Balloon, dom)
+
 	memStats := stats.Balloon
 	if float64(memStats.Available) == 0 {
 		memStats.Available = memStats.Current
 	}
+	// balloon stats are in KiB
 	ch <- prometheus.MustNewConstMetric(c.memTotal,
 		prometheus.GaugeValue,
-		float64(memStats.Available),
-		uuid)
+		float64(memStats.Available*1024),
+		dom.labels("balloon")...)
 	ch <- prometheus.MustNewConstMetric(c.memUsed,
 		prometheus.GaugeValue,
-		float64(memStats.Available-memStats.Unused),
-		uuid)
+		float64((memStats.Available-memStats.Unused)*1024),
+		dom.labels("balloon")...)
 	ch <- prometheus.MustNewConstMetric(c.memAvailable,
 		prometheus.GaugeValue,
-		float64(memStats.Usable),
-		uuid)
+		float64(memStats.Usable*1024),
+		dom.labels("balloon")...)
+	if *compatV1Metrics {
+		ch <- prometheus.MustNewConstMetric(c.v1MemTotal,
+			prometheus.GaugeValue,
+			float64(memStats.Available),
+			dom.labels()...)
+		ch <- prometheus.MustNewConstMetric(c.v1MemUsed,
+			prometheus.GaugeValue,
+			float64(memStats.Available-memStats.Unused),
+			dom.labels()...)
+		ch <- prometheus.MustNewConstMetric(c.v1MemAvailable,
+			prometheus.GaugeValue,
+			float64(memStats.Usable),
+			dom.labels()...)
+	}
+	return c.updateStatsPeriod(ch, stats.Domain, dom)
+}
+
+func (c *memCollector) updateStatsPeriod(ch chan<- prometheus.Metric, domain *libvirt.Domain, dom *domainMeta) error {
+	if dom.xmlErr != nil {
+		return dom.xmlErr
+	}
+	if !dom.xml.HasBalloon() {
+		return nil
+	}
+	// the live XML holds the period, even if it wasn't persisted
+	ch <- prometheus.MustNewConstMetric(c.statsPeriod,
+		prometheus.GaugeValue,
+		float64(dom.xml.Devices.MemBalloon.Stats.Period),
+		dom.labels()...)
+
+	period := int(memStatsPeriod.Seconds())
+	if period <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for uuid, seen := range c.periodSet {
+		if now.Sub(seen) > periodSetTTL {
+			delete(c.periodSet, uuid)
+		}
+	}
+	switch live := dom.xml.Devices.MemBalloon.Stats.Period; {
+	case live == 0:
+		// the period is lost when the domain is restarted
+		if err := domain.SetMemoryStatsPeriod(period, libvirt.DOMAIN_MEM_LIVE); err != nil {
+			return err
+		}
+		c.periodSet[dom.uuid] = now
+	case live != period:
+		// set by someone else
+		delete(c.periodSet, dom.uuid)
+	}
+	if _, ok := c.periodSet[dom.uuid]; ok {
+		c.periodSet[dom.uuid] = now
+		ch <- prometheus.MustNewConstMetric(c.statsPeriodSet,
+			prometheus.GaugeValue,
+			1,
+			dom.labels()...)
+	}
 	return nil
 }
+
+func (c *memCollector) updateBalloon(ch chan<- prometheus.Metric, b *libvirt.DomainStatsBalloon, dom *domainMeta) {
+	// only the fields reported by the balloon driver are set, sizes are
+	// in KiB
+	kib := func(desc *prometheus.Desc, valueType prometheus.ValueType, set bool, v uint64) {
+		if set {
+			ch <- prometheus.MustNewConstMetric(desc, valueType, float64(v*1024), dom.labels()...)
+		}
+	}
+	count := func(desc *prometheus.Desc, set bool, v uint64) {
+		if set {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(v), dom.labels()...)
+		}
+	}
+	kib(c.balloonCurrent, prometheus.GaugeValue, b.CurrentSet, b.Current)
+	kib(c.balloonMaximum, prometheus.GaugeValue, b.MaximumSet, b.Maximum)
+	kib(c.balloonSwapIn, prometheus.CounterValue, b.SwapInSet, b.SwapIn)
+	kib(c.balloonSwapOut, prometheus.CounterValue, b.SwapOutSet, b.SwapOut)
+	count(c.balloonMajorFault, b.MajorFaultSet, b.MajorFault)
+	count(c.balloonMinorFault, b.MinorFaultSet, b.MinorFault)
+	kib(c.balloonUnused, prometheus.GaugeValue, b.UnusedSet, b.Unused)
+	kib(c.balloonAvailable, prometheus.GaugeValue, b.AvailableSet, b.Available)
+	kib(c.balloonUsable, prometheus.GaugeValue, b.UsableSet, b.Usable)
+	kib(c.balloonRss, prometheus.GaugeValue, b.RssSet, b.Rss)
+	kib(c.balloonDiskCaches, prometheus.GaugeValue, b.DiskCachesSet, b.DiskCaches)
+	count(c.balloonHugetlbPgAlloc, b.HugetlbPgAllocSet, b.HugetlbPgAlloc)
+	count(c.balloonHugetlbPgFail, b.HugetlbPgFailSet, b.HugetlbPgFail)
+
+	// last_update is 0 until the guest reported once
+	if b.LastUpdateSet && b.LastUpdate > 0 {
+		ch <- prometheus.MustNewConstMetric(c.balloonLastUpdate,
+			prometheus.GaugeValue,
+			float64(b.LastUpdate),
+			dom.labels()...)
+		ch <- prometheus.MustNewConstMetric(c.balloonAge,
+			prometheus.GaugeValue,
+			time.Since(time.Unix(int64(b.LastUpdate), 0)).Seconds(),
+			dom.labels()...)
+	}
+}
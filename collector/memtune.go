@@ -0,0 +1,163 @@
+package collector
+
+import (
+	"io/ioutil"
+	"prometheus_libvirt_exporter/internal"
+	"sync"
+
+	"github.com/libvirt/libvirt-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type memtuneCollector struct {
+	hardLimit        *prometheus.Desc
+	softLimit        *prometheus.Desc
+	swapHardLimit    *prometheus.Desc
+	minGuarantee     *prometheus.Desc
+	backingHugepages *prometheus.Desc
+	backingPageSize  *prometheus.Desc
+	backingLocked    *prometheus.Desc
+	backingShared    *prometheus.Desc
+	backingMemfd     *prometheus.Desc
+
+	mu                  sync.Mutex
+	defaultHugepageSize uint64 // of the host, read once
+}
+
+func init() {
+	registerCollector("memtune", newMemtuneCollector)
+}
+
+func newMemtuneCollector() (Collector, error) {
+	c := &memtuneCollector{
+		hardLimit: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "hard_limit_bytes"),
+			"Maximum memory the domain can use on the host in bytes, not set if unlimited.",
+			domainLabelNames(), nil),
+		softLimit: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "soft_limit_bytes"),
+			"Memory the domain is limited to under host memory contention in bytes, not set if unlimited.",
+			domainLabelNames(), nil),
+		swapHardLimit: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "swap_hard_limit_bytes"),
+			"Maximum memory plus swap the domain can use on the host in bytes, not set if unlimited.",
+			domainLabelNames(), nil),
+		minGuarantee: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "min_guarantee_bytes"),
+			"Memory guaranteed to the domain in bytes.",
+			domainLabelNames(), nil),
+		backingHugepages: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "backing_hugepages"),
+			"Whether the memory of the domain is backed by huge pages.",
+			domainLabelNames(), nil),
+		backingPageSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "backing_page_size_bytes"),
+			"Huge page size backing the memory of the guest NUMA nodes in nodeset, all nodes if empty. The default huge page size of the host if the domain names none.",
+			domainLabelNames("nodeset"), nil),
+		backingLocked: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "backing_locked"),
+			"Whether the memory of the domain is locked in host memory.",
+			domainLabelNames(), nil),
+		backingShared: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "backing_shared"),
+			"Whether the memory of the domain is mapped shared, e.g. for vhost-user.",
+			domainLabelNames(), nil),
+		backingMemfd: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memCollectorSubsystem, "backing_memfd"),
+			"Whether the memory of the domain is backed by memfd.",
+			domainLabelNames(), nil),
+	}
+
+	return c, nil
+}
+
+func (c *memtuneCollector) CollectsInactive() {}
+
+func (c *memtuneCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
+	if dom.xmlErr != nil {
+		return dom.xmlErr
+	}
+	backing := dom.xml.MemoryBacking
+	hugepages := backing.HugePages != nil
+	ch <- prometheus.MustNewConstMetric(c.backingHugepages,
+		prometheus.GaugeValue,
+		boolFloat(hugepages),
+		dom.labels()...)
+	if hugepages && len(backing.HugePages.Pages) == 0 {
+		// libvirt backs the memory with the default huge page size
+		size, err := c.getDefaultHugepageSize()
+		if err != nil {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(c.backingPageSize,
+			prometheus.GaugeValue,
+			float64(size),
+			dom.labels("")...)
+	} else if hugepages {
+		for _, page := range backing.HugePages.Pages {
+			ch <- prometheus.MustNewConstMetric(c.backingPageSize,
+				prometheus.GaugeValue,
+				float64(page.Bytes()),
+				dom.labels(page.Nodeset)...)
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.backingLocked,
+		prometheus.GaugeValue,
+		boolFloat(backing.Locked != nil),
+		dom.labels()...)
+	ch <- prometheus.MustNewConstMetric(c.backingShared,
+		prometheus.GaugeValue,
+		boolFloat(backing.Access.Mode == "shared"),
+		dom.labels()...)
+	ch <- prometheus.MustNewConstMetric(c.backingMemfd,
+		prometheus.GaugeValue,
+		boolFloat(backing.Source.Type == "memfd"),
+		dom.labels()...)
+
+	// live values of running domains, the config otherwise
+	params, err := stats.Domain.GetMemoryParameters(libvirt.DOMAIN_AFFECT_CURRENT)
+	if err != nil {
+		return err
+	}
+	// limits are in KiB
+	for _, p := range []struct {
+		desc  *prometheus.Desc
+		set   bool
+		value uint64
+	}{
+		{c.hardLimit, params.HardLimitSet, params.HardLimit},
+		{c.softLimit, params.SoftLimitSet, params.SoftLimit},
+		{c.swapHardLimit, params.SwapHardLimitSet, params.SwapHardLimit},
+		{c.minGuarantee, params.MinGuaranteeSet, params.MinGuarantee},
+	} {
+		if !p.set || p.value >= libvirt.DOMAIN_MEMORY_PARAM_UNLIMITED {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(p.desc,
+			prometheus.GaugeValue,
+			float64(p.value*1024),
+			dom.labels()...)
+	}
+	return nil
+}
+
+// getDefaultHugepageSize returns the default huge page size of the host, the
+// exporter runs on the libvirt host.
+func (c *memtuneCollector) getDefaultHugepageSize() (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.defaultHugepageSize != 0 {
+		return c.defaultHugepageSize, nil
+	}
+
+	data, err := ioutil.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0, err
+	}
+	m, err := internal.GetMeminfo(data)
+	if err != nil {
+		return 0, err
+	}
+	c.defaultHugepageSize = m.Hugepagesize
+	return c.defaultHugepageSize, nil
+}
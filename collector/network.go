@@ -18,6 +18,16 @@ type networkCollector struct {
 	transmitPackets *prometheus.Desc
 	transmitErrors  *prometheus.Desc
 	transmitDrops   *prometheus.Desc
+
+	// v1 names, see --compat.v1-metrics
+	v1ReceiveBytes    *prometheus.Desc
+	v1ReceivePackets  *prometheus.Desc
+	v1ReceiveErrors   *prometheus.Desc
+	v1ReceiveDrops    *prometheus.Desc
+	v1TransmitBytes   *prometheus.Desc
+	v1TransmitPackets *prometheus.Desc
+	v1TransmitErrors  *prometheus.Desc
+	v1TransmitDrops   *prometheus.Desc
 }
 
 func init() {
@@ -27,87 +37,120 @@ func init() {
 func newNetworkCollector() (Collector, error) {
 	c := &networkCollector{
 		receiveBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, networkCollectorSubsystem, "receive_bytes",
-			),
-			"",
-			[]string{"uuid", "target_device"}, nil),
+			prometheus.BuildFQName(namespace, networkCollectorSubsystem, "receive_bytes_total"),
+			"Bytes received by the interface.",
+			domainLabelNames("target_device"), nil),
 		receivePackets: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, networkCollectorSubsystem, "receive_packets"),
-			"",
-			[]string{"uuid", "target_device"}, nil),
+			prometheus.BuildFQName(namespace, networkCollectorSubsystem, "receive_packets_total"),
+			"Packets received by the interface.",
+			domainLabelNames("target_device"), nil),
 		receiveErrors: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, networkCollectorSubsystem, "receive_errors"),
-			"",
-			[]string{"uuid", "target_device"}, nil),
+			prometheus.BuildFQName(namespace, networkCollectorSubsystem, "receive_errors_total"),
+			"Receive errors of the interface.",
+			domainLabelNames("target_device"), nil),
 		receiveDrops: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, networkCollectorSubsystem, "receive_drops"),
-			"",
-			[]string{"uuid", "target_device"}, nil),
+			prometheus.BuildFQName(namespace, networkCollectorSubsystem, "receive_drops_total"),
+			"Received packets dropped by the interface.",
+			domainLabelNames("target_device"), nil),
 		transmitBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, networkCollectorSubsystem, "transmit_bytes",
-			),
-			"",
-			[]string{"uuid", "target_device"}, nil),
+			prometheus.BuildFQName(namespace, networkCollectorSubsystem, "transmit_bytes_total"),
+			"Bytes transmitted by the interface.",
+			domainLabelNames("target_device"), nil),
 		transmitPackets: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, networkCollectorSubsystem, "transmit_packets"),
-			"",
-			[]string{"uuid", "target_device"}, nil),
+			prometheus.BuildFQName(namespace, networkCollectorSubsystem, "transmit_packets_total"),
+			"Packets transmitted by the interface.",
+			domainLabelNames("target_device"), nil),
 		transmitErrors: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, networkCollectorSubsystem, "transmit_errors"),
-			"",
-			[]string{"uuid", "target_device"}, nil),
+			prometheus.BuildFQName(namespace, networkCollectorSubsystem, "transmit_errors_total"),
+			"Transmit errors of the interface.",
+			domainLabelNames("target_device"), nil),
 		transmitDrops: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, networkCollectorSubsystem, "transmit_drops"),
-			"",
-			[]string{"uuid", "target_device"}, nil),
+			prometheus.BuildFQName(namespace, networkCollectorSubsystem, "transmit_drops_total"),
+			"Transmitted packets dropped by the interface.",
+			domainLabelNames("target_device"), nil),
+
+		v1ReceiveBytes:    v1Desc(networkCollectorSubsystem, "receive_bytes", domainLabelNames("target_device")),
+		v1ReceivePackets:  v1Desc(networkCollectorSubsystem, "receive_packets", domainLabelNames("target_device")),
+		v1ReceiveErrors:   v1Desc(networkCollectorSubsystem, "receive_errors", domainLabelNames("target_device")),
+		v1ReceiveDrops:    v1Desc(networkCollectorSubsystem, "receive_drops", domainLabelNames("target_device")),
+		v1TransmitBytes:   v1Desc(networkCollectorSubsystem, "transmit_bytes", domainLabelNames("target_device")),
+		v1TransmitPackets: v1Desc(networkCollectorSubsystem, "transmit_packets", domainLabelNames("target_device")),
+		v1TransmitErrors:  v1Desc(networkCollectorSubsystem, "transmit_errors", domainLabelNames("target_device")),
+		v1TransmitDrops:   v1Desc(networkCollectorSubsystem, "transmit_drops", domainLabelNames("target_device")),
 	}
 
 	return c, nil
 }
 
-func (c *networkCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, uuid string, rs rpcSet) error {
+func (c *networkCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
 	netStats := stats.Net
 	for _, v := range netStats {
 		ch <- prometheus.MustNewConstMetric(c.receiveBytes,
-			prometheus.GaugeValue,
+			prometheus.CounterValue,
 			float64(v.RxBytes),
-			uuid,
-			v.Name)
+			dom.labels(v.Name)...)
 		ch <- prometheus.MustNewConstMetric(c.receivePackets,
-			prometheus.GaugeValue,
+			prometheus.CounterValue,
 			float64(v.RxPkts),
-			uuid,
-			v.Name)
+			dom.labels(v.Name)...)
 		ch <- prometheus.MustNewConstMetric(c.receiveErrors,
-			prometheus.GaugeValue,
+			prometheus.CounterValue,
 			float64(v.RxErrs),
-			uuid,
-			v.Name)
+			dom.labels(v.Name)...)
 		ch <- prometheus.MustNewConstMetric(c.receiveDrops,
-			prometheus.GaugeValue,
+			prometheus.CounterValue,
 			float64(v.RxDrop),
-			uuid,
-			v.Name)
+			dom.labels(v.Name)...)
 		ch <- prometheus.MustNewConstMetric(c.transmitBytes,
-			prometheus.GaugeValue,
+			prometheus.CounterValue,
 			float64(v.TxBytes),
-			uuid,
-			v.Name)
+			dom.labels(v.Name)...)
 		ch <- prometheus.MustNewConstMetric(c.transmitPackets,
-			prometheus.GaugeValue,
+			prometheus.CounterValue,
 			float64(v.TxPkts),
-			uuid,
-			v.Name)
+			dom.labels(v.Name)...)
 		ch <- prometheus.MustNewConstMetric(c.transmitErrors,
-			prometheus.GaugeValue,
+			prometheus.CounterValue,
 			float64(v.TxErrs),
-			uuid,
-			v.Name)
+			dom.labels(v.Name)...)
 		ch <- prometheus.MustNewConstMetric(c.transmitDrops,
-			prometheus.GaugeValue,
+			prometheus.CounterValue,
 			float64(v.TxDrop),
-			uuid,
-			v.Name)
+			dom.labels(v.Name)...)
+		if *compatV1Metrics {
+			ch <- prometheus.MustNewConstMetric(c.v1ReceiveBytes,
+				prometheus.GaugeValue,
+				float64(v.RxBytes),
+				dom.labels(v.Name)...)
+			ch <- prometheus.MustNewConstMetric(c.v1ReceivePackets,
+				prometheus.GaugeValue,
+				float64(v.RxPkts),
+				dom.labels(v.Name)...)
+			ch <- prometheus.MustNewConstMetric(c.v1ReceiveErrors,
+				prometheus.GaugeValue,
+				float64(v.RxErrs),
+				dom.labels(v.Name)...)
+			ch <- prometheus.MustNewConstMetric(c.v1ReceiveDrops,
+				prometheus.GaugeValue,
+				float64(v.RxDrop),
+				dom.labels(v.Name)...)
+			ch <- prometheus.MustNewConstMetric(c.v1TransmitBytes,
+				prometheus.GaugeValue,
+				float64(v.TxBytes),
+				dom.labels(v.Name)...)
+			ch <- prometheus.MustNewConstMetric(c.v1TransmitPackets,
+				prometheus.GaugeValue,
+				float64(v.TxPkts),
+				dom.labels(v.Name)...)
+			ch <- prometheus.MustNewConstMetric(c.v1TransmitErrors,
+				prometheus.GaugeValue,
+				float64(v.TxErrs),
+				dom.labels(v.Name)...)
+			ch <- prometheus.MustNewConstMetric(c.v1TransmitDrops,
+				prometheus.GaugeValue,
+				float64(v.TxDrop),
+				dom.labels(v.Name)...)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,160 @@
+package collector
+
+import (
+	"prometheus_libvirt_exporter/internal"
+	"strconv"
+	"sync"
+
+	"github.com/libvirt/libvirt-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	numaCollectorSubsystem = "numa"
+)
+
+var numaMemModes = map[libvirt.DomainNumatuneMemMode]string{
+	libvirt.DOMAIN_NUMATUNE_MEM_STRICT:      "strict",
+	libvirt.DOMAIN_NUMATUNE_MEM_PREFERRED:   "preferred",
+	libvirt.DOMAIN_NUMATUNE_MEM_INTERLEAVE:  "interleave",
+	libvirt.DOMAIN_NUMATUNE_MEM_RESTRICTIVE: "restrictive",
+}
+
+type numaCollector struct {
+	vcpuAffinity     *prometheus.Desc
+	emulatorAffinity *prometheus.Desc
+	iothreadAffinity *prometheus.Desc
+	memory           *prometheus.Desc
+	mismatch         *prometheus.Desc
+
+	// host CPU to NUMA node, read once from the capabilities
+	mu        sync.Mutex
+	hostNodes map[int]int
+}
+
+func init() {
+	registerCollector("numa", newNUMACollector)
+}
+
+func newNUMACollector() (Collector, error) {
+	c := &numaCollector{
+		vcpuAffinity: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, vcpuCollectorSubsystem, "affinity_info"),
+			"Host CPUs the vCPU is pinned to, always 1.",
+			domainLabelNames("vcpu", "cpuset"), nil),
+		emulatorAffinity: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "emulator", "affinity_info"),
+			"Host CPUs the emulator threads are pinned to, always 1.",
+			domainLabelNames("cpuset"), nil),
+		iothreadAffinity: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "iothread", "affinity_info"),
+			"Host CPUs the IOThread is pinned to, always 1.",
+			domainLabelNames("iothread", "cpuset"), nil),
+		memory: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, numaCollectorSubsystem, "memory_info"),
+			"NUMA memory mode and host nodes the memory is placed on, always 1.",
+			domainLabelNames("mode", "nodeset"), nil),
+		mismatch: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, numaCollectorSubsystem, "mismatch"),
+			"Whether vCPUs may run on host NUMA nodes outside the memory nodeset.",
+			domainLabelNames(), nil),
+	}
+
+	return c, nil
+}
+
+func (c *numaCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
+	vcpuMaps, err := stats.Domain.GetVcpuPinInfo(libvirt.DOMAIN_AFFECT_LIVE)
+	if err != nil {
+		return err
+	}
+	for i, cpuMap := range vcpuMaps {
+		ch <- prometheus.MustNewConstMetric(c.vcpuAffinity,
+			prometheus.GaugeValue,
+			1,
+			dom.labels(strconv.Itoa(i), internal.FormatCPUSet(cpuMap))...)
+	}
+
+	emulatorMap, err := stats.Domain.GetEmulatorPinInfo(libvirt.DOMAIN_AFFECT_LIVE)
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.emulatorAffinity,
+		prometheus.GaugeValue,
+		1,
+		dom.labels(internal.FormatCPUSet(emulatorMap))...)
+
+	iothreads, err := stats.Domain.GetIOThreadInfo(libvirt.DOMAIN_AFFECT_LIVE)
+	if err != nil && !isUnsupported(err) {
+		return err
+	}
+	for _, t := range iothreads {
+		ch <- prometheus.MustNewConstMetric(c.iothreadAffinity,
+			prometheus.GaugeValue,
+			1,
+			dom.labels(strconv.FormatUint(uint64(t.IOThreadID), 10), internal.FormatCPUSet(t.CpuMap))...)
+	}
+
+	params, err := stats.Domain.GetNumaParameters(libvirt.DOMAIN_AFFECT_LIVE)
+	// e.g. without the cpuset cgroup controller there is no NUMA info
+	if isUnsupported(err) || isLibvirtError(err, libvirt.ERR_OPERATION_INVALID) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	mode := ""
+	if params.ModeSet {
+		mode = numaMemModes[params.Mode]
+	}
+	ch <- prometheus.MustNewConstMetric(c.memory,
+		prometheus.GaugeValue,
+		1,
+		dom.labels(mode, params.Nodeset)...)
+
+	// without a nodeset the memory is placed anywhere
+	if !params.NodesetSet || params.Nodeset == "" {
+		return nil
+	}
+	memNodes, err := internal.ParseCPUSet(params.Nodeset)
+	if err != nil {
+		return err
+	}
+	hostNodes, err := c.getHostNodes(stats.Domain)
+	if err != nil {
+		return err
+	}
+	mismatch := 0.0
+	for _, cpuMap := range vcpuMaps {
+		for cpu, pinned := range cpuMap {
+			if node, ok := hostNodes[cpu]; pinned && ok && !memNodes[node] {
+				mismatch = 1
+			}
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.mismatch,
+		prometheus.GaugeValue,
+		mismatch,
+		dom.labels()...)
+	return nil
+}
+
+func (c *numaCollector) getHostNodes(dom *libvirt.Domain) (map[int]int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hostNodes != nil {
+		return c.hostNodes, nil
+	}
+
+	conn, err := dom.DomainGetConnect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	caps, err := conn.GetCapabilities()
+	if err != nil {
+		return nil, err
+	}
+	c.hostNodes, err = internal.GetHostCPUNodes(caps)
+	return c.hostNodes, err
+}
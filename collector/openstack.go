@@ -0,0 +1,80 @@
+package collector
+
+import (
+	"github.com/libvirt/libvirt-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	openstackCollectorSubsystem = "domain_openstack"
+)
+
+type openstackCollector struct {
+	info              *prometheus.Desc
+	flavorVCPUs       *prometheus.Desc
+	flavorMemoryBytes *prometheus.Desc
+	flavorDiskBytes   *prometheus.Desc
+}
+
+func init() {
+	registerCollector("openstack", newOpenstackCollector)
+}
+
+func newOpenstackCollector() (Collector, error) {
+	c := &openstackCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, openstackCollectorSubsystem, "info"),
+			"OpenStack Nova instance of the domain, always 1.",
+			domainLabelNames("instance_name", "flavor", "project_id", "project_name", "user_id", "user_name"), nil),
+		flavorVCPUs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, openstackCollectorSubsystem, "flavor_vcpus"),
+			"Number of vCPUs of the Nova flavor.",
+			domainLabelNames(), nil),
+		flavorMemoryBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, openstackCollectorSubsystem, "flavor_memory_bytes"),
+			"RAM size of the Nova flavor in bytes.",
+			domainLabelNames(), nil),
+		flavorDiskBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, openstackCollectorSubsystem, "flavor_disk_bytes"),
+			"Root disk size of the Nova flavor in bytes.",
+			domainLabelNames(), nil),
+	}
+
+	return c, nil
+}
+
+func (c *openstackCollector) CollectsInactive() {}
+
+func (c *openstackCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
+	if dom.xmlErr != nil {
+		return dom.xmlErr
+	}
+	nova, ok := dom.xml.Nova()
+	if !ok {
+		return nil
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.info,
+		prometheus.GaugeValue,
+		1,
+		dom.labels(nova.Name,
+			nova.Flavor.Name,
+			nova.Owner.Project.UUID,
+			nova.Owner.Project.Name,
+			nova.Owner.User.UUID,
+			nova.Owner.User.Name)...)
+	ch <- prometheus.MustNewConstMetric(c.flavorVCPUs,
+		prometheus.GaugeValue,
+		float64(nova.Flavor.VCPUs),
+		dom.labels()...)
+	// nova keeps memory in MiB and disks in GiB
+	ch <- prometheus.MustNewConstMetric(c.flavorMemoryBytes,
+		prometheus.GaugeValue,
+		float64(nova.Flavor.Memory<<20),
+		dom.labels()...)
+	ch <- prometheus.MustNewConstMetric(c.flavorDiskBytes,
+		prometheus.GaugeValue,
+		float64(nova.Flavor.Disk<<30),
+		dom.labels()...)
+	return nil
+}
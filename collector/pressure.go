@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"prometheus_libvirt_exporter/collector/qga"
+	"prometheus_libvirt_exporter/internal"
+
+	"github.com/libvirt/libvirt-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	pressureCollectorSubsystem = "guest_pressure"
+)
+
+var pressureResources = []string{"cpu", "memory", "io"}
+
+type pressureCollector struct {
+	avg   *prometheus.Desc
+	stall *prometheus.Desc
+}
+
+func init() {
+	registerCollector("pressure", newPressureCollector)
+}
+
+func newPressureCollector() (Collector, error) {
+	c := &pressureCollector{
+		avg: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pressureCollectorSubsystem, "ratio"),
+			"Share of time some or all tasks in the guest were stalled on the resource, averaged over the window.",
+			domainLabelNames("resource", "type", "window"), nil),
+		stall: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pressureCollectorSubsystem, "stalled_seconds_total"),
+			"Time some or all tasks in the guest were stalled on the resource.",
+			domainLabelNames("resource", "type"), nil),
+	}
+
+	return c, nil
+}
+
+func (c *pressureCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
+	if !dom.rs.GuestFileRead {
+		return nil
+	}
+	for _, resource := range pressureResources {
+		data, err := qga.ReadFile(stats.Domain, "/proc/pressure/"+resource)
+		// kernels before 4.20 or booted with psi=0
+		if qga.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		p, err := internal.GetPressure(data)
+		if err != nil {
+			return err
+		}
+		c.updateStat(ch, dom, resource, "some", p.Some)
+		if p.Full != nil {
+			c.updateStat(ch, dom, resource, "full", p.Full)
+		}
+	}
+	return nil
+}
+
+func (c *pressureCollector) updateStat(ch chan<- prometheus.Metric, dom *domainMeta, resource, typ string, s *internal.PressureStat) {
+	for window, v := range map[string]float64{"10s": s.Avg10, "60s": s.Avg60, "300s": s.Avg300} {
+		ch <- prometheus.MustNewConstMetric(c.avg,
+			prometheus.GaugeValue,
+			v,
+			dom.labels(resource, typ, window)...)
+	}
+	ch <- prometheus.MustNewConstMetric(c.stall,
+		prometheus.CounterValue,
+		s.Total,
+		dom.labels(resource, typ)...)
+}
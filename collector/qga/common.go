@@ -2,9 +2,77 @@ package qga
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/libvirt/libvirt-go"
 )
 
+// Error is returned when a guest agent command fails. Class is the QMP error
+// class of the agent reply if there is one, else it's derived from the
+// libvirt error code.
+type Error struct {
+	Command string
+	Class   string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("guest agent command %s failed (%s): %v", e.Command, e.Class, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// errorClass classifies a failed agent command by the libvirt error code.
+// libvirt reports errors of the agent itself as internal errors of the qemu
+// driver and doesn't pass on their QMP class.
+func errorClass(err error) string {
+	var virErr libvirt.Error
+	if !errors.As(err, &virErr) {
+		return "GenericError"
+	}
+	switch virErr.Code {
+	case libvirt.ERR_AGENT_UNRESPONSIVE:
+		return "AgentUnresponsive"
+	case libvirt.ERR_AGENT_UNSYNCED:
+		return "AgentUnsynced"
+	case libvirt.ERR_OPERATION_TIMEOUT:
+		return "Timeout"
+	case libvirt.ERR_OPERATION_UNSUPPORTED, libvirt.ERR_NO_SUPPORT:
+		return "NotSupported"
+	case libvirt.ERR_OPERATION_INVALID:
+		return "AgentUnavailable"
+	}
+	return "GenericError"
+}
+
+// isAgentError reports whether the agent itself failed the command, as
+// opposed to libvirt failing to pass it on.
+func isAgentError(err error) bool {
+	var virErr libvirt.Error
+	return errors.As(err, &virErr) && virErr.Domain == libvirt.FROM_QEMU && virErr.Code == libvirt.ERR_INTERNAL_ERROR
+}
+
+// IsNotExist reports whether the guest couldn't open the file, e.g. /proc
+// files of features disabled in the kernel.
+func IsNotExist(err error) bool {
+	var qgaErr *Error
+	if !errors.As(err, &qgaErr) {
+		return false
+	}
+	return qgaErr.Class == "OpenFailed"
+}
+
+// agentReply holds the error of an agent reply, in case libvirt passes one
+// on instead of failing the command.
+type agentReply struct {
+	Error *struct {
+		Class string `json:"class"`
+		Desc  string `json:"desc"`
+	} `json:"error"`
+}
+
 // guest-read-file
 type fileOpen struct {
 	Execute   string `json:"execute"`
@@ -87,7 +155,28 @@ func qemuAgentCommand(dom *libvirt.Domain, cmdObj interface{}) (string, error) {
 	}
 	cmdRet, err := dom.QemuAgentCommand(string(cmd), -1, 0)
 	if err != nil {
-		return "", err
+		return "", &Error{commandName(cmd), errorClass(err), err}
+	}
+	reply := agentReply{}
+	if err := json.Unmarshal([]byte(cmdRet), &reply); err == nil && reply.Error != nil {
+		return "", &Error{commandName(cmd), reply.Error.Class, errors.New(reply.Error.Desc)}
 	}
 	return cmdRet, nil
 }
+
+// commandName returns the name of the marshalled agent command.
+func commandName(cmd []byte) string {
+	name := struct {
+		Execute string `json:"execute"`
+	}{}
+	_ = json.Unmarshal(cmd, &name)
+	return name.Execute
+}
+
+// unmarshalReturn decodes the reply of an agent command.
+func unmarshalReturn(command, ret string, v interface{}) error {
+	if err := json.Unmarshal([]byte(ret), v); err != nil {
+		return &Error{command, "InvalidResponse", err}
+	}
+	return nil
+}
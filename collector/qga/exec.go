@@ -18,7 +18,7 @@ func Exec(dom *libvirt.Domain, cmd GuestExecArg) ([]byte, error) {
 		return nil, err
 	}
 	retExecObj := retGuestExec{}
-	err = json.Unmarshal([]byte(retExec), &retExecObj)
+	err = unmarshalReturn(execObj.Execute, retExec, &retExecObj)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -30,7 +30,7 @@ func Exec(dom *libvirt.Domain, cmd GuestExecArg) ([]byte, error) {
 	if data != nil {
 		return data, err
 	}
-	return nil, errors.New("failed to get exec return data")
+	return nil, &Error{execObj.Execute, "ExecPending", errors.New("failed to get exec return data")}
 }
 
 func execStatus(dom *libvirt.Domain, pid int) ([]byte, error) {
@@ -51,7 +51,7 @@ func execStatus(dom *libvirt.Domain, pid int) ([]byte, error) {
 	if !retExecStatusObj.Return.Exited {
 		if retExecStatusObj.Return.Exitcode != 0 {
 			errData, _ := base64.StdEncoding.DecodeString(retExecStatusObj.Return.ErrData)
-			return nil, errors.New(string(errData))
+			return nil, &Error{execStatusObj.Execute, "ExecFailed", errors.New(string(errData))}
 		}
 		return nil, nil
 	}
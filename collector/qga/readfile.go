@@ -2,10 +2,17 @@ package qga
 
 import (
 	"encoding/base64"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/libvirt/libvirt-go"
 )
 
+const (
+	readChunkSize = 16384
+	// files are read up to readChunkSize * readMaxChunks bytes
+	readMaxChunks = 64
+)
+
 func ReadFile(dom *libvirt.Domain, path string) ([]byte, error) {
 	data := []byte{}
 
@@ -17,14 +24,19 @@ func ReadFile(dom *libvirt.Domain, path string) ([]byte, error) {
 		}{Path: path, Mode: "r"}}
 
 	retOpen, err := qemuAgentCommand(dom, fileOpenObj)
+	// the agent fails guest-file-open when open(2) fails in the guest
+	var qgaErr *Error
+	if errors.As(err, &qgaErr) && isAgentError(qgaErr.Err) {
+		qgaErr.Class = "OpenFailed"
+	}
 	if err != nil {
-		return []byte{}, nil
+		return []byte{}, err
 	}
 
 	retOpenObj := fileOpenRet{}
-	err = json.Unmarshal([]byte(retOpen), &retOpenObj)
+	err = unmarshalReturn(fileOpenObj.Execute, retOpen, &retOpenObj)
 	if err != nil {
-		return []byte{}, nil
+		return []byte{}, err
 	}
 
 	// close file
@@ -46,24 +58,28 @@ func ReadFile(dom *libvirt.Domain, path string) ([]byte, error) {
 		struct {
 			Handle int `json:"handle"`
 			Count  int `json:"count"`
-		}{Handle: retOpenObj.Return, Count: 2048},
+		}{Handle: retOpenObj.Return, Count: readChunkSize},
 	}
-	for i := 0; i < 10; i++ {
+	for i := 0; i < readMaxChunks; i++ {
 		retRead, err := qemuAgentCommand(dom, fileReadObj)
 		if err != nil {
-			return []byte{}, nil
+			return []byte{}, err
 		}
 		retReadObj := fileReadRet{}
-		err = json.Unmarshal([]byte(retRead), &retReadObj)
+		err = unmarshalReturn(fileReadObj.Execute, retRead, &retReadObj)
+		if err != nil {
+			return []byte{}, err
+		}
+		contentSlice, err := base64.StdEncoding.DecodeString(retReadObj.Return.Bufb64)
 		if err != nil {
-			return []byte{}, nil
+			return []byte{}, &Error{fileReadObj.Execute, "InvalidResponse", err}
 		}
-		contentSlice, _ := base64.StdEncoding.DecodeString(retReadObj.Return.Bufb64)
 		data = append(data, contentSlice...)
 		if retReadObj.Return.Eof {
-			break
+			return data, nil
 		}
 	}
 
-	return data, nil
+	// a truncated file would be parsed as if it was complete
+	return []byte{}, fmt.Errorf("%s is larger than %d bytes", path, readChunkSize*readMaxChunks)
 }
@@ -0,0 +1,226 @@
+package collector
+
+import (
+	"prometheus_libvirt_exporter/internal"
+
+	"github.com/libvirt/libvirt-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	snapshotCollectorSubsystem   = "domain_snapshot"
+	checkpointCollectorSubsystem = "domain_checkpoint"
+)
+
+type snapshotCollector struct {
+	snapshots        *prometheus.Desc
+	snapshotOldest   *prometheus.Desc
+	snapshotNewest   *prometheus.Desc
+	currentInfo      *prometheus.Desc
+	currentDepth     *prometheus.Desc
+	checkpoints      *prometheus.Desc
+	checkpointOldest *prometheus.Desc
+	checkpointNewest *prometheus.Desc
+}
+
+func init() {
+	registerCollector("snapshot", newSnapshotCollector)
+}
+
+func newSnapshotCollector() (Collector, error) {
+	c := &snapshotCollector{
+		snapshots: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, snapshotCollectorSubsystem, "count"),
+			"Number of snapshots of the domain.",
+			domainLabelNames(), nil),
+		snapshotOldest: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, snapshotCollectorSubsystem, "oldest_creation_timestamp_seconds"),
+			"Creation time of the oldest snapshot of the domain.",
+			domainLabelNames(), nil),
+		snapshotNewest: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, snapshotCollectorSubsystem, "newest_creation_timestamp_seconds"),
+			"Creation time of the newest snapshot of the domain.",
+			domainLabelNames(), nil),
+		currentInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, snapshotCollectorSubsystem, "current_info"),
+			"Current snapshot of the domain and whether it holds only the disks (disk-only) or the memory and device state too (full), always 1.",
+			domainLabelNames("snapshot", "type"), nil),
+		currentDepth: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, snapshotCollectorSubsystem, "current_depth"),
+			"Number of parents of the current snapshot of the domain.",
+			domainLabelNames(), nil),
+		checkpoints: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, checkpointCollectorSubsystem, "count"),
+			"Number of checkpoints of the domain.",
+			domainLabelNames(), nil),
+		checkpointOldest: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, checkpointCollectorSubsystem, "oldest_creation_timestamp_seconds"),
+			"Creation time of the oldest checkpoint of the domain.",
+			domainLabelNames(), nil),
+		checkpointNewest: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, checkpointCollectorSubsystem, "newest_creation_timestamp_seconds"),
+			"Creation time of the newest checkpoint of the domain.",
+			domainLabelNames(), nil),
+	}
+
+	return c, nil
+}
+
+func (c *snapshotCollector) CollectsInactive() {}
+
+func (c *snapshotCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
+	if err := c.updateSnapshots(ch, stats.Domain, dom); err != nil {
+		return err
+	}
+	return c.updateCheckpoints(ch, stats.Domain, dom)
+}
+
+func (c *snapshotCollector) updateSnapshots(ch chan<- prometheus.Metric, domain *libvirt.Domain, dom *domainMeta) error {
+	snapshots, err := domain.ListAllSnapshots(0)
+	// e.g. hypervisors without snapshot support
+	if isUnsupported(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, s := range snapshots {
+			s.Free()
+		}
+	}()
+
+	// the creation times are only in the XML
+	byName := map[string]internal.SnapshotXML{}
+	var times []int64
+	for i := range snapshots {
+		data, err := snapshots[i].GetXMLDesc(0)
+		if err != nil {
+			return err
+		}
+		s, err := internal.GetSnapshotXML(data)
+		if err != nil {
+			return err
+		}
+		byName[s.Name] = s
+		times = append(times, s.CreationTime)
+	}
+	current, err := currentSnapshot(domain, byName)
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.snapshots,
+		prometheus.GaugeValue,
+		float64(len(snapshots)),
+		dom.labels()...)
+	c.updateTimes(ch, dom, c.snapshotOldest, c.snapshotNewest, times)
+	if current == nil {
+		return nil
+	}
+	typ := "full"
+	if current.DiskOnly() {
+		typ = "disk-only"
+	}
+	ch <- prometheus.MustNewConstMetric(c.currentInfo,
+		prometheus.GaugeValue,
+		1,
+		dom.labels(current.Name, typ)...)
+	// a parent missing from the list ends the chain
+	depth := 0
+	for name := current.Parent.Name; name != "" && depth < len(byName); name = byName[name].Parent.Name {
+		depth++
+	}
+	ch <- prometheus.MustNewConstMetric(c.currentDepth,
+		prometheus.GaugeValue,
+		float64(depth),
+		dom.labels()...)
+	return nil
+}
+
+// currentSnapshot returns the current snapshot of the domain out of byName,
+// nil if there is none.
+func currentSnapshot(domain *libvirt.Domain, byName map[string]internal.SnapshotXML) (*internal.SnapshotXML, error) {
+	has, err := domain.HasCurrentSnapshot(0)
+	if err != nil || !has {
+		return nil, err
+	}
+	snapshot, err := domain.SnapshotCurrent(0)
+	// deleted since
+	if isLibvirtError(err, libvirt.ERR_NO_DOMAIN_SNAPSHOT) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer snapshot.Free()
+	name, err := snapshot.GetName()
+	if err != nil {
+		return nil, err
+	}
+	s, ok := byName[name]
+	if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func (c *snapshotCollector) updateCheckpoints(ch chan<- prometheus.Metric, domain *libvirt.Domain, dom *domainMeta) error {
+	checkpoints, err := domain.ListAllCheckpoints(0)
+	// checkpoints need libvirt 6.0 and a hypervisor supporting them
+	if isUnsupported(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, cp := range checkpoints {
+			cp.Free()
+		}
+	}()
+
+	var times []int64
+	for i := range checkpoints {
+		data, err := checkpoints[i].GetXMLDesc(libvirt.DOMAIN_CHECKPOINT_XML_NO_DOMAIN)
+		if err != nil {
+			return err
+		}
+		cp, err := internal.GetSnapshotXML(data)
+		if err != nil {
+			return err
+		}
+		times = append(times, cp.CreationTime)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.checkpoints,
+		prometheus.GaugeValue,
+		float64(len(checkpoints)),
+		dom.labels()...)
+	c.updateTimes(ch, dom, c.checkpointOldest, c.checkpointNewest, times)
+	return nil
+}
+
+// updateTimes reports the oldest and newest of the creation times, if any.
+func (c *snapshotCollector) updateTimes(ch chan<- prometheus.Metric, dom *domainMeta, oldest, newest *prometheus.Desc, times []int64) {
+	if len(times) == 0 {
+		return
+	}
+	min, max := times[0], times[0]
+	for _, t := range times[1:] {
+		if t < min {
+			min = t
+		}
+		if t > max {
+			max = t
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(oldest,
+		prometheus.GaugeValue,
+		float64(min),
+		dom.labels()...)
+	ch <- prometheus.MustNewConstMetric(newest,
+		prometheus.GaugeValue,
+		float64(max),
+		dom.labels()...)
+}
@@ -0,0 +1,178 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/libvirt/libvirt-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	storagePoolCollectorSubsystem   = "storage_pool"
+	storageVolumeCollectorSubsystem = "storage_volume"
+)
+
+var storageVolumes = kingpin.Flag(
+	"collector.storage.volumes",
+	"Also report the capacity and allocation of every storage volume.",
+).Bool()
+
+var storagePoolStates = map[libvirt.StoragePoolState]string{
+	libvirt.STORAGE_POOL_INACTIVE:     "inactive",
+	libvirt.STORAGE_POOL_BUILDING:     "building",
+	libvirt.STORAGE_POOL_RUNNING:      "running",
+	libvirt.STORAGE_POOL_DEGRADED:     "degraded",
+	libvirt.STORAGE_POOL_INACCESSIBLE: "inaccessible",
+}
+
+type storageCollector struct {
+	poolState        *prometheus.Desc
+	poolCapacity     *prometheus.Desc
+	poolAllocation   *prometheus.Desc
+	poolAvailable    *prometheus.Desc
+	volumeCapacity   *prometheus.Desc
+	volumeAllocation *prometheus.Desc
+}
+
+func init() {
+	registerCollector("storage", newStorageCollector)
+}
+
+func newStorageCollector() (Collector, error) {
+	c := &storageCollector{
+		poolState: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, storagePoolCollectorSubsystem, "state"),
+			"Current state of the storage pool, always 1.",
+			[]string{"pool", "state"}, nil),
+		poolCapacity: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, storagePoolCollectorSubsystem, "capacity_bytes"),
+			"Size of the storage pool in bytes.",
+			[]string{"pool"}, nil),
+		poolAllocation: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, storagePoolCollectorSubsystem, "allocation_bytes"),
+			"Space allocated in the storage pool in bytes.",
+			[]string{"pool"}, nil),
+		poolAvailable: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, storagePoolCollectorSubsystem, "available_bytes"),
+			"Space left for new volumes in the storage pool in bytes.",
+			[]string{"pool"}, nil),
+		volumeCapacity: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, storageVolumeCollectorSubsystem, "capacity_bytes"),
+			"Size of the storage volume as seen by its users in bytes.",
+			[]string{"pool", "volume"}, nil),
+		volumeAllocation: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, storageVolumeCollectorSubsystem, "allocation_bytes"),
+			"Space allocated to the storage volume in bytes.",
+			[]string{"pool", "volume"}, nil),
+	}
+
+	return c, nil
+}
+
+// Update does nothing, storage pools aren't per domain, see UpdateHost.
+func (c *storageCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
+	return nil
+}
+
+func (c *storageCollector) UpdateHost(ch chan<- prometheus.Metric, conn *libvirt.Connect) error {
+	pools, err := conn.ListAllStoragePools(0)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, p := range pools {
+			p.Free()
+		}
+	}()
+
+	// a pool failing, e.g. one deleted meanwhile, doesn't hide the others
+	var errs []error
+	for i := range pools {
+		name, err := pools[i].GetName()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		info, err := pools[i].GetInfo()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("pool %s: %w", name, err))
+			continue
+		}
+		state, ok := storagePoolStates[info.State]
+		if !ok {
+			state = "unknown"
+		}
+		ch <- prometheus.MustNewConstMetric(c.poolState,
+			prometheus.GaugeValue,
+			1,
+			name, state)
+		// sizes are only known while the pool is running
+		if info.State != libvirt.STORAGE_POOL_RUNNING && info.State != libvirt.STORAGE_POOL_DEGRADED {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.poolCapacity,
+			prometheus.GaugeValue,
+			float64(info.Capacity),
+			name)
+		ch <- prometheus.MustNewConstMetric(c.poolAllocation,
+			prometheus.GaugeValue,
+			float64(info.Allocation),
+			name)
+		ch <- prometheus.MustNewConstMetric(c.poolAvailable,
+			prometheus.GaugeValue,
+			float64(info.Available),
+			name)
+
+		if *storageVolumes {
+			if err := c.updateVolumes(ch, &pools[i], name); err != nil {
+				errs = append(errs, fmt.Errorf("pool %s: %w", name, err))
+			}
+		}
+	}
+	return joinErrors(errs, len(pools), "pools")
+}
+
+func (c *storageCollector) updateVolumes(ch chan<- prometheus.Metric, pool *libvirt.StoragePool, poolName string) error {
+	vols, err := pool.ListAllStorageVolumes(0)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, v := range vols {
+			v.Free()
+		}
+	}()
+
+	var errs []error
+	for i := range vols {
+		name, err := vols[i].GetName()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		info, err := vols[i].GetInfo()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("volume %s: %w", name, err))
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.volumeCapacity,
+			prometheus.GaugeValue,
+			float64(info.Capacity),
+			poolName, name)
+		ch <- prometheus.MustNewConstMetric(c.volumeAllocation,
+			prometheus.GaugeValue,
+			float64(info.Allocation),
+			poolName, name)
+	}
+	return joinErrors(errs, len(vols), "volumes")
+}
+
+// joinErrors returns nil without errs, else an error counting them and
+// wrapping the first one.
+func joinErrors(errs []error, total int, what string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d %s failed, first: %w", len(errs), total, what, errs[0])
+}
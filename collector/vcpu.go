@@ -0,0 +1,104 @@
+package collector
+
+import (
+	"strconv"
+
+	"github.com/libvirt/libvirt-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	vcpuCollectorSubsystem = "vcpu"
+)
+
+var vcpuStates = map[libvirt.VcpuState]string{
+	libvirt.VCPU_OFFLINE: "offline",
+	libvirt.VCPU_RUNNING: "running",
+	libvirt.VCPU_BLOCKED: "blocked",
+}
+
+type vcpuCollector struct {
+	state  *prometheus.Desc
+	time   *prometheus.Desc
+	wait   *prometheus.Desc
+	delay  *prometheus.Desc
+	halted *prometheus.Desc
+}
+
+func init() {
+	registerCollector("vcpu", newVCPUCollector)
+}
+
+func newVCPUCollector() (Collector, error) {
+	c := &vcpuCollector{
+		state: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, vcpuCollectorSubsystem, "state"),
+			"Current state of the vCPU, always 1.",
+			domainLabelNames("vcpu", "state"), nil),
+		time: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, vcpuCollectorSubsystem, "time_seconds_total"),
+			"Time the vCPU spent running.",
+			domainLabelNames("vcpu"), nil),
+		wait: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, vcpuCollectorSubsystem, "wait_seconds_total"),
+			"Time the vCPU wanted to run, but the host scheduler was running something else.",
+			domainLabelNames("vcpu"), nil),
+		delay: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, vcpuCollectorSubsystem, "delay_seconds_total"),
+			"Time the vCPU spent waiting in the host run queue, the steal time seen by the guest.",
+			domainLabelNames("vcpu"), nil),
+		halted: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, vcpuCollectorSubsystem, "halted"),
+			"Whether the vCPU is halted.",
+			domainLabelNames("vcpu"), nil),
+	}
+
+	return c, nil
+}
+
+func (c *vcpuCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
+	// stats are indexed by the vCPU number
+	for i, v := range stats.Vcpu {
+		vcpu := strconv.Itoa(i)
+		if v.StateSet {
+			state, ok := vcpuStates[v.State]
+			if !ok {
+				state = "unknown"
+			}
+			ch <- prometheus.MustNewConstMetric(c.state,
+				prometheus.GaugeValue,
+				1,
+				dom.labels(vcpu, state)...)
+		}
+		// times are in nanoseconds
+		if v.TimeSet {
+			ch <- prometheus.MustNewConstMetric(c.time,
+				prometheus.CounterValue,
+				float64(v.Time)/1e9,
+				dom.labels(vcpu)...)
+		}
+		if v.WaitSet {
+			ch <- prometheus.MustNewConstMetric(c.wait,
+				prometheus.CounterValue,
+				float64(v.Wait)/1e9,
+				dom.labels(vcpu)...)
+		}
+		if v.DelaySet {
+			ch <- prometheus.MustNewConstMetric(c.delay,
+				prometheus.CounterValue,
+				float64(v.Delay)/1e9,
+				dom.labels(vcpu)...)
+		}
+		if v.HaltedSet {
+			halted := 0.0
+			if v.Halted {
+				halted = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.halted,
+				prometheus.GaugeValue,
+				halted,
+				dom.labels(vcpu)...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package collector
+
+import (
+	"prometheus_libvirt_exporter/collector/qga"
+	"prometheus_libvirt_exporter/internal"
+	"regexp"
+	"sort"
+
+	"github.com/libvirt/libvirt-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	vmstatCollectorSubsystem = "guest_vmstat"
+)
+
+// paging, swapping, OOM kills, compaction and reclaim, the per zone
+// allocstall counters of older kernels included
+var vmstatFieldsRE = regexp.MustCompile(`^(pgpgin|pgpgout|pswpin|pswpout|pgmajfault|oom_kill|compact_.*|pgscan_.*|pgsteal_.*|allocstall.*)$`)
+
+type vmstatCollector struct{}
+
+func init() {
+	registerCollector("vmstat", newVmstatCollector)
+}
+
+func newVmstatCollector() (Collector, error) {
+	return &vmstatCollector{}, nil
+}
+
+func (c *vmstatCollector) Update(ch chan<- prometheus.Metric, stats *libvirt.DomainStats, dom *domainMeta) error {
+	if !dom.rs.GuestFileRead {
+		return nil
+	}
+	data, err := qga.ReadFile(stats.Domain, "/proc/vmstat")
+	if err != nil {
+		return err
+	}
+	vmstat, err := internal.GetVmstat(data)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(vmstat))
+	for name := range vmstat {
+		if vmstatFieldsRE.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ch <- prometheus.MustNewConstMetric(
+			prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, vmstatCollectorSubsystem, name+"_total"),
+				"/proc/vmstat counter "+name+" of the guest.",
+				domainLabelNames(), nil),
+			prometheus.CounterValue,
+			float64(vmstat[name]),
+			dom.labels()...)
+	}
+	return nil
+}
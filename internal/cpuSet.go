@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FormatCPUSet turns a libvirt CPU map into the cpuset notation, e.g. "0-3,8".
+func FormatCPUSet(cpuMap []bool) string {
+	var parts []string
+	for i := 0; i < len(cpuMap); i++ {
+		if !cpuMap[i] {
+			continue
+		}
+		j := i
+		for j+1 < len(cpuMap) && cpuMap[j+1] {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(i))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", i, j))
+		}
+		i = j
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseCPUSet parses the cpuset notation used by libvirt for CPUs and NUMA
+// nodes, exclusions such as "0-7,^3" are supported and apply regardless of
+// their position.
+func ParseCPUSet(set string) (map[int]bool, error) {
+	ret := map[int]bool{}
+	excluded := map[int]bool{}
+	for _, part := range strings.Split(set, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		target := ret
+		if strings.HasPrefix(part, "^") {
+			target = excluded
+			part = part[1:]
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse cpuset %q: %w", set, err)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, fmt.Errorf("couldn't parse cpuset %q: %w", set, err)
+			}
+		}
+		if first < 0 || last < first {
+			return nil, fmt.Errorf("couldn't parse cpuset %q: invalid range %q", set, part)
+		}
+		for i := first; i <= last; i++ {
+			target[i] = true
+		}
+	}
+	for i := range excluded {
+		delete(ret, i)
+	}
+	return ret, nil
+}
+
+// GetHostCPUNodes maps each host CPU to its NUMA node, read from the host
+// topology of the capabilities XML.
+func GetHostCPUNodes(data string) (map[int]int, error) {
+	caps := struct {
+		Cells []struct {
+			ID   int `xml:"id,attr"`
+			CPUs []struct {
+				ID int `xml:"id,attr"`
+			} `xml:"cpus>cpu"`
+		} `xml:"host>topology>cells>cell"`
+	}{}
+	if err := xml.Unmarshal([]byte(data), &caps); err != nil {
+		return nil, fmt.Errorf("couldn't parse capabilities xml: %w", err)
+	}
+	nodes := map[int]int{}
+	for _, cell := range caps.Cells {
+		for _, cpu := range cell.CPUs {
+			nodes[cpu.ID] = cell.ID
+		}
+	}
+	return nodes, nil
+}
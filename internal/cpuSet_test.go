@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormatCPUSet(t *testing.T) {
+	tests := []struct {
+		cpuMap []bool
+		want   string
+	}{
+		{nil, ""},
+		{[]bool{false, false}, ""},
+		{[]bool{true}, "0"},
+		{[]bool{true, true, true, true}, "0-3"},
+		{[]bool{true, false, true}, "0,2"},
+		{[]bool{false, true, true, false, false, true, true, true}, "1-2,5-7"},
+	}
+	for _, test := range tests {
+		if got := FormatCPUSet(test.cpuMap); got != test.want {
+			t.Errorf("%v: got %q, want %q", test.cpuMap, got, test.want)
+		}
+	}
+}
+
+func TestParseCPUSet(t *testing.T) {
+	tests := []struct {
+		set     string
+		want    map[int]bool
+		wantErr bool
+	}{
+		{set: "", want: map[int]bool{}},
+		{set: "3", want: map[int]bool{3: true}},
+		{set: "0-3", want: map[int]bool{0: true, 1: true, 2: true, 3: true}},
+		{set: "0-1, 4", want: map[int]bool{0: true, 1: true, 4: true}},
+		{set: "0-3,^1", want: map[int]bool{0: true, 2: true, 3: true}},
+		// exclusions apply after all inclusions
+		{set: "^1,0-3", want: map[int]bool{0: true, 2: true, 3: true}},
+		{set: "0-7,^2-5", want: map[int]bool{0: true, 1: true, 6: true, 7: true}},
+		{set: "^1", want: map[int]bool{}},
+		{set: "a", wantErr: true},
+		{set: "0-b", wantErr: true},
+		{set: "3-1", wantErr: true},
+		{set: "-1", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseCPUSet(test.set)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error", test.set)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.set, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.set, got, test.want)
+		}
+	}
+}
+
+func TestFormatParseCPUSet(t *testing.T) {
+	cpuMap := []bool{true, true, false, true, false, false, true, true}
+	set, err := ParseCPUSet(FormatCPUSet(cpuMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, pinned := range cpuMap {
+		if set[i] != pinned {
+			t.Errorf("cpu %d: got %v, want %v", i, set[i], pinned)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package internal
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+type memoryValue struct {
+	Value uint64 `xml:",chardata"`
+	Unit  string `xml:"unit,attr"`
+}
+
+// Bytes returns the value in bytes, libvirt defaults to KiB.
+func (m memoryValue) Bytes() uint64 {
+	return m.Value * unitMultiplier(m.Unit)
+}
+
+type DomainXML struct {
+	// the document as read
+	Raw string `xml:"-"`
+
+	Name          string      `xml:"name"`
+	UUID          string      `xml:"uuid"`
+	Title         string      `xml:"title"`
+	Memory        memoryValue `xml:"memory"`
+	CurrentMemory memoryValue `xml:"currentMemory"`
+	MemoryBacking struct {
+		HugePages *struct {
+			Pages []HugePage `xml:"page"`
+		} `xml:"hugepages"`
+		Locked *struct{} `xml:"locked"`
+		Source struct {
+			Type string `xml:"type,attr"`
+		} `xml:"source"`
+		Access struct {
+			Mode string `xml:"mode,attr"`
+		} `xml:"access"`
+	} `xml:"memoryBacking"`
+	VCPU struct {
+		Value   uint `xml:",chardata"`
+		Current uint `xml:"current,attr"`
+	} `xml:"vcpu"`
+	OS struct {
+		Type struct {
+			Value   string `xml:",chardata"`
+			Arch    string `xml:"arch,attr"`
+			Machine string `xml:"machine,attr"`
+		} `xml:"type"`
+	} `xml:"os"`
+	CPU struct {
+		Mode  string `xml:"mode,attr"`
+		Model string `xml:"model"`
+	} `xml:"cpu"`
+	Devices struct {
+		Emulator   string `xml:"emulator"`
+		Disks      []Disk `xml:"disk"`
+		MemBalloon struct {
+			Model string `xml:"model,attr"`
+			Stats struct {
+				Period int `xml:"period,attr"`
+			} `xml:"stats"`
+		} `xml:"memballoon"`
+	} `xml:"devices"`
+	Metadata struct {
+		// any namespace, see NovaInstance
+		Instances []NovaInstance `xml:"instance"`
+	} `xml:"metadata"`
+}
+
+// HugePage is a huge page size backing the memory of the guest NUMA nodes in
+// Nodeset, all of them if empty.
+type HugePage struct {
+	Size    uint64 `xml:"size,attr"`
+	Unit    string `xml:"unit,attr"`
+	Nodeset string `xml:"nodeset,attr"`
+}
+
+// Bytes returns the page size in bytes.
+func (p HugePage) Bytes() uint64 {
+	return p.Size * unitMultiplier(p.Unit)
+}
+
+// Disk is a disk device of the domain.
+type Disk struct {
+	// file, block, network or volume
+	Type string `xml:"type,attr"`
+	// disk, cdrom, floppy or lun
+	Device string `xml:"device,attr"`
+	Driver struct {
+		Name  string `xml:"name,attr"`
+		Type  string `xml:"type,attr"`
+		Cache string `xml:"cache,attr"`
+	} `xml:"driver"`
+	Source struct {
+		File     string `xml:"file,attr"`
+		Dev      string `xml:"dev,attr"`
+		Dir      string `xml:"dir,attr"`
+		Protocol string `xml:"protocol,attr"`
+		Name     string `xml:"name,attr"`
+		Pool     string `xml:"pool,attr"`
+		Volume   string `xml:"volume,attr"`
+	} `xml:"source"`
+	Target struct {
+		Dev string `xml:"dev,attr"`
+		Bus string `xml:"bus,attr"`
+	} `xml:"target"`
+	Serial   string    `xml:"serial"`
+	ReadOnly *struct{} `xml:"readonly"`
+	// set in the live XML while a copy or active commit job runs
+	Mirror *struct {
+		Job string `xml:"job,attr"`
+		// yes once the job is ready, abort or pivot while it is ended
+		Ready string `xml:"ready,attr"`
+	} `xml:"mirror"`
+}
+
+// SourcePath returns the file or block device backing a local disk.
+func (d Disk) SourcePath() string {
+	switch {
+	case d.Source.File != "":
+		return d.Source.File
+	case d.Source.Dev != "":
+		return d.Source.Dev
+	}
+	return d.Source.Dir
+}
+
+// SourcePool returns the pool and image of a network or storage pool
+// volume disk: the RBD pool and image, the iSCSI target and LUN, or the NFS
+// export and file.
+func (d Disk) SourcePool() (pool, image string) {
+	if d.Type == "volume" {
+		return d.Source.Pool, d.Source.Volume
+	}
+	// "pool/image", "iqn.../lun" and "/export/file"
+	name := d.Source.Name
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// Disk returns the disk with the target device name dev.
+func (d DomainXML) Disk(dev string) (Disk, bool) {
+	for _, disk := range d.Devices.Disks {
+		if disk.Target.Dev == dev {
+			return disk, true
+		}
+	}
+	return Disk{}, false
+}
+
+// HasBalloon reports whether the domain has a memory balloon device.
+func (d DomainXML) HasBalloon() bool {
+	return d.Devices.MemBalloon.Model != "" && d.Devices.MemBalloon.Model != "none"
+}
+
+const novaNamespacePrefix = "http://openstack.org/xmlns/libvirt/nova/"
+
+// NovaInstance is the metadata OpenStack Nova writes into its domains.
+type NovaInstance struct {
+	XMLName xml.Name
+	Name    string `xml:"name"`
+	Flavor  struct {
+		Name      string `xml:"name,attr"`
+		Memory    uint64 `xml:"memory"`
+		Disk      uint64 `xml:"disk"`
+		Swap      uint64 `xml:"swap"`
+		Ephemeral uint64 `xml:"ephemeral"`
+		VCPUs     uint64 `xml:"vcpus"`
+	} `xml:"flavor"`
+	Owner struct {
+		User struct {
+			Name string `xml:",chardata"`
+			UUID string `xml:"uuid,attr"`
+		} `xml:"user"`
+		Project struct {
+			Name string `xml:",chardata"`
+			UUID string `xml:"uuid,attr"`
+		} `xml:"project"`
+	} `xml:"owner"`
+}
+
+// Nova returns the nova:instance metadata, if the domain is managed by Nova.
+func (d DomainXML) Nova() (NovaInstance, bool) {
+	for _, instance := range d.Metadata.Instances {
+		if strings.HasPrefix(instance.XMLName.Space, novaNamespacePrefix) {
+			return instance, true
+		}
+	}
+	return NovaInstance{}, false
+}
+
+// Parse the domain XML as returned by virDomainGetXMLDesc.
+func GetDomainXML(data string) (DomainXML, error) {
+	dom := DomainXML{Raw: data}
+	if err := xml.Unmarshal([]byte(data), &dom); err != nil {
+		return DomainXML{}, fmt.Errorf("couldn't parse domain xml: %w", err)
+	}
+	return dom, nil
+}
+
+// CurrentVCPUs returns the number of vCPUs the domain was started with.
+func (d DomainXML) CurrentVCPUs() uint {
+	if d.VCPU.Current != 0 {
+		return d.VCPU.Current
+	}
+	return d.VCPU.Value
+}
+
+// CPUModel returns the configured CPU model, or the CPU mode for host-model
+// and host-passthrough CPUs which have no model.
+func (d DomainXML) CPUModel() string {
+	if d.CPU.Model != "" {
+		return d.CPU.Model
+	}
+	return d.CPU.Mode
+}
+
+func unitMultiplier(unit string) uint64 {
+	switch strings.ToLower(unit) {
+	case "b", "bytes":
+		return 1
+	case "kb":
+		return 1000
+	case "mb":
+		return 1000 * 1000
+	case "gb":
+		return 1000 * 1000 * 1000
+	case "tb":
+		return 1000 * 1000 * 1000 * 1000
+	case "m", "mib":
+		return 1 << 20
+	case "g", "gib":
+		return 1 << 30
+	case "t", "tib":
+		return 1 << 40
+	default:
+		// k, KiB and no unit at all
+		return 1 << 10
+	}
+}
@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Meminfo holds the fields of /proc/meminfo, sizes in bytes.
+type Meminfo struct {
+	MemTotal     uint64
+	MemFree      uint64
+	MemAvailable uint64
+	// MemAvailable is missing before Linux 3.14.
+	MemAvailableSet bool
+	Buffers         uint64
+	Cached          uint64
+	Slab            uint64
+	Dirty           uint64
+	SwapTotal       uint64
+	SwapFree        uint64
+	CommittedAS     uint64
+	// Number of huge pages, not bytes.
+	HugePagesTotal uint64
+	HugePagesFree  uint64
+	HugePagesRsvd  uint64
+	HugePagesSurp  uint64
+	Hugepagesize   uint64
+}
+
+// GetMeminfo parses /proc/meminfo read from a guest.
+func GetMeminfo(data []byte) (Meminfo, error) {
+	var m Meminfo
+	fields := map[string]*uint64{
+		"MemTotal":        &m.MemTotal,
+		"MemFree":         &m.MemFree,
+		"MemAvailable":    &m.MemAvailable,
+		"Buffers":         &m.Buffers,
+		"Cached":          &m.Cached,
+		"Slab":            &m.Slab,
+		"Dirty":           &m.Dirty,
+		"SwapTotal":       &m.SwapTotal,
+		"SwapFree":        &m.SwapFree,
+		"Committed_AS":    &m.CommittedAS,
+		"HugePages_Total": &m.HugePagesTotal,
+		"HugePages_Free":  &m.HugePagesFree,
+		"HugePages_Rsvd":  &m.HugePagesRsvd,
+		"HugePages_Surp":  &m.HugePagesSurp,
+		"Hugepagesize":    &m.Hugepagesize,
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// e.g. "MemTotal:        8008368 kB" or "HugePages_Total:       0"
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 2 {
+			continue
+		}
+		key := strings.TrimSuffix(parts[0], ":")
+		field, ok := fields[key]
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return Meminfo{}, fmt.Errorf("couldn't parse %s in meminfo: %w", key, err)
+		}
+		if len(parts) == 3 && parts[2] == "kB" {
+			value *= 1024
+		}
+		*field = value
+		if key == "MemAvailable" {
+			m.MemAvailableSet = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Meminfo{}, fmt.Errorf("couldn't parse meminfo: %w", err)
+	}
+	if m.MemTotal == 0 {
+		return Meminfo{}, fmt.Errorf("couldn't parse meminfo: no MemTotal")
+	}
+	return m, nil
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"testing"
+)
+
+// /proc/meminfo of a guest running Linux 5.15, shortened
+const procMeminfo = `MemTotal:        8148528 kB
+MemFree:          312256 kB
+MemAvailable:    5210364 kB
+Buffers:          248196 kB
+Cached:          4683716 kB
+SwapCached:         4248 kB
+Active:          3321044 kB
+Inactive:        3715068 kB
+Dirty:               564 kB
+Writeback:             0 kB
+Slab:             512904 kB
+SReclaimable:     401436 kB
+SwapTotal:       2097148 kB
+SwapFree:        2051324 kB
+Committed_AS:    4825128 kB
+HugePages_Total:      64
+HugePages_Free:       60
+HugePages_Rsvd:        2
+HugePages_Surp:        1
+Hugepagesize:       2048 kB
+Hugetlb:          131072 kB
+DirectMap4k:      251748 kB
+DirectMap2M:     8136704 kB
+`
+
+func TestGetMeminfo(t *testing.T) {
+	m, err := GetMeminfo([]byte(procMeminfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Meminfo{
+		MemTotal:        8148528 * 1024,
+		MemFree:         312256 * 1024,
+		MemAvailable:    5210364 * 1024,
+		MemAvailableSet: true,
+		Buffers:         248196 * 1024,
+		Cached:          4683716 * 1024,
+		Slab:            512904 * 1024,
+		Dirty:           564 * 1024,
+		SwapTotal:       2097148 * 1024,
+		SwapFree:        2051324 * 1024,
+		CommittedAS:     4825128 * 1024,
+		// the pool sizes have no unit, they count pages
+		HugePagesTotal: 64,
+		HugePagesFree:  60,
+		HugePagesRsvd:  2,
+		HugePagesSurp:  1,
+		Hugepagesize:   2048 * 1024,
+	}
+	if m != want {
+		t.Errorf("got %+v, want %+v", m, want)
+	}
+}
+
+func TestGetMeminfoWithoutMemAvailable(t *testing.T) {
+	// MemAvailable was added in Linux 3.14
+	m, err := GetMeminfo([]byte("MemTotal:        1016324 kB\nMemFree:          120444 kB\nBuffers:           61520 kB\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.MemAvailableSet || m.MemAvailable != 0 {
+		t.Errorf("got MemAvailable %d, set %t", m.MemAvailable, m.MemAvailableSet)
+	}
+	if m.MemTotal != 1016324*1024 || m.Buffers != 61520*1024 {
+		t.Errorf("got MemTotal %d, Buffers %d", m.MemTotal, m.Buffers)
+	}
+}
+
+func TestGetMeminfoErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"empty":         "",
+		"no MemTotal":   "MemFree:          312256 kB\n",
+		"zero MemTotal": "MemTotal:              0 kB\n",
+		"bad number":    "MemTotal:        8148528 kB\nMemFree:          -1 kB\n",
+	} {
+		if _, err := GetMeminfo([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PressureStat is one line of a /proc/pressure file, the share of time tasks
+// were stalled on a resource.
+type PressureStat struct {
+	// Ratios over the last 10s, 60s and 300s.
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	// Total stall time in seconds.
+	Total float64
+}
+
+// Pressure holds the "some" and "full" lines, cpu has no full line before
+// Linux 5.13.
+type Pressure struct {
+	Some *PressureStat
+	Full *PressureStat
+}
+
+// GetPressure parses a file of /proc/pressure read from a guest.
+func GetPressure(data []byte) (Pressure, error) {
+	var p Pressure
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// e.g. "some avg10=0.12 avg60=0.05 avg300=0.01 total=123456"
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 0 {
+			continue
+		}
+		if len(parts) == 1 {
+			return Pressure{}, fmt.Errorf("couldn't parse pressure line %q: no fields", parts[0])
+		}
+		stat, err := parsePressureStat(parts[1:])
+		if err != nil {
+			return Pressure{}, err
+		}
+		switch parts[0] {
+		case "some":
+			p.Some = stat
+		case "full":
+			p.Full = stat
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Pressure{}, fmt.Errorf("couldn't parse pressure: %w", err)
+	}
+	if p.Some == nil {
+		return Pressure{}, fmt.Errorf("couldn't parse pressure: no some line")
+	}
+	return p, nil
+}
+
+func parsePressureStat(fields []string) (*PressureStat, error) {
+	stat := &PressureStat{}
+	for _, field := range fields {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("couldn't parse pressure field %q", field)
+		}
+		value, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse pressure field %q: %w", field, err)
+		}
+		// averages are percentages, total is in microseconds
+		switch kv[0] {
+		case "avg10":
+			stat.Avg10 = value / 100
+		case "avg60":
+			stat.Avg60 = value / 100
+		case "avg300":
+			stat.Avg300 = value / 100
+		case "total":
+			stat.Total = value / 1e6
+		}
+	}
+	return stat, nil
+}
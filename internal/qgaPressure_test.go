@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"math"
+	"testing"
+)
+
+// equalStat compares the stats with a tolerance for the unit conversions.
+func equalStat(a, b PressureStat) bool {
+	for _, d := range []float64{a.Avg10 - b.Avg10, a.Avg60 - b.Avg60, a.Avg300 - b.Avg300, a.Total - b.Total} {
+		if math.Abs(d) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGetPressure(t *testing.T) {
+	// /proc/pressure/io of a guest running Linux 5.15
+	p, err := GetPressure([]byte(`some avg10=1.53 avg60=0.87 avg300=0.22 total=14837521
+full avg10=0.50 avg60=0.25 avg300=0.05 total=9876543
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Some == nil || p.Full == nil {
+		t.Fatalf("got some %v, full %v", p.Some, p.Full)
+	}
+	// percentages become ratios, microseconds become seconds
+	if want := (PressureStat{Avg10: 0.0153, Avg60: 0.0087, Avg300: 0.0022, Total: 14.837521}); !equalStat(*p.Some, want) {
+		t.Errorf("got some %+v, want %+v", *p.Some, want)
+	}
+	if want := (PressureStat{Avg10: 0.005, Avg60: 0.0025, Avg300: 0.0005, Total: 9.876543}); !equalStat(*p.Full, want) {
+		t.Errorf("got full %+v, want %+v", *p.Full, want)
+	}
+}
+
+func TestGetPressureCPUWithoutFull(t *testing.T) {
+	// /proc/pressure/cpu has no full line before Linux 5.13
+	p, err := GetPressure([]byte("some avg10=0.00 avg60=0.00 avg300=0.00 total=1234\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Some == nil || !equalStat(*p.Some, PressureStat{Total: 0.001234}) {
+		t.Errorf("got some %+v", p.Some)
+	}
+	if p.Full != nil {
+		t.Errorf("got full %+v, want none", p.Full)
+	}
+}
+
+func TestGetPressureErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"empty":               "",
+		"no some line":        "full avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+		"some without fields": "some\n",
+		"full without fields": "some avg10=0.00 avg60=0.00 avg300=0.00 total=0\nfull\n",
+		"field without value": "some avg10 avg60=0.00 avg300=0.00 total=0\n",
+		"bad number":          "some avg10=0.00 avg60=0.00 avg300=0.00 total=x\n",
+	} {
+		if _, err := GetPressure([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -11,6 +11,9 @@ import (
 
 const userHZ = 100
 
+// maxStatLineSize is the longest line of /proc/stat GetStat accepts.
+const maxStatLineSize = 1 << 20
+
 type CPUStat struct {
 	User      float64
 	Nice      float64
@@ -24,57 +27,44 @@ type CPUStat struct {
 	GuestNice float64
 }
 
-//type SoftIRQStat struct {
-//	Hi          uint64
-//	Timer       uint64
-//	NetTx       uint64
-//	NetRx       uint64
-//	Block       uint64
-//	BlockIoPoll uint64
-//	Tasklet     uint64
-//	Sched       uint64
-//	Hrtimer     uint64
-//	Rcu         uint64
-//}
+type SoftIRQStat struct {
+	Hi          uint64
+	Timer       uint64
+	NetTx       uint64
+	NetRx       uint64
+	Block       uint64
+	BlockIoPoll uint64
+	Tasklet     uint64
+	Sched       uint64
+	Hrtimer     uint64
+	Rcu         uint64
+}
 
 type Stat struct {
 	// Boot time in seconds since the Epoch.
-	//BootTime uint64
+	BootTime uint64
 	// Summed up cpu statistics.
 	CPUTotal CPUStat
 	// Per-CPU statistics.
 	CPU []CPUStat
 	// Number of times interrupts were handled, which contains numbered and unnumbered IRQs.
-	//IRQTotal uint64
+	IRQTotal uint64
 	// Number of times a numbered IRQ was triggered.
-	//IRQ []uint64
+	IRQ []uint64
 	// Number of times a context switch happened.
-	//ContextSwitches uint64
+	ContextSwitches uint64
 	// Number of times a process was created.
-	//ProcessCreated uint64
+	ProcessCreated uint64
 	// Number of processes currently running.
-	//ProcessesRunning uint64
+	ProcessesRunning uint64
 	// Number of processes currently blocked (waiting for IO).
-	//ProcessesBlocked uint64
+	ProcessesBlocked uint64
 	// Number of times a softirq was scheduled.
-	//SoftIRQTotal uint64
+	SoftIRQTotal uint64
 	// Detailed softirq statistics.
-	//SoftIRQ SoftIRQStat
+	SoftIRQ SoftIRQStat
 }
 
-//func ReadFileNoStat(filename string) ([]byte, error) {
-//	const maxBufferSize = 1024 * 512
-//
-//	f, err := os.Open(filename)
-//	if err != nil {
-//		return nil, err
-//	}
-//	defer f.Close()
-//
-//	reader := io.LimitReader(f, maxBufferSize)
-//	return ioutil.ReadAll(reader)
-//}
-
 func parseCPUStat(line string) (CPUStat, int64, error) {
 	cpuStat := CPUStat{}
 	var cpu string
@@ -115,33 +105,30 @@ func parseCPUStat(line string) (CPUStat, int64, error) {
 	return cpuStat, cpuID, nil
 }
 
-//func parseSoftIRQStat(line string) (SoftIRQStat, uint64, error) {
-//	softIRQStat := SoftIRQStat{}
-//	var total uint64
-//	var prefix string
-//
-//	_, err := fmt.Sscanf(line, "%s %d %d %d %d %d %d %d %d %d %d %d",
-//		&prefix, &total,
-//		&softIRQStat.Hi, &softIRQStat.Timer, &softIRQStat.NetTx, &softIRQStat.NetRx,
-//		&softIRQStat.Block, &softIRQStat.BlockIoPoll,
-//		&softIRQStat.Tasklet, &softIRQStat.Sched,
-//		&softIRQStat.Hrtimer, &softIRQStat.Rcu)
-//
-//	if err != nil {
-//		return SoftIRQStat{}, 0, fmt.Errorf("couldn't parse %q (softirq): %w", line, err)
-//	}
-//
-//	return softIRQStat, total, nil
-//}
+func parseSoftIRQStat(line string) (SoftIRQStat, uint64, error) {
+	softIRQStat := SoftIRQStat{}
+	var total uint64
+	var prefix string
 
-func GetStat(data []byte) (stat Stat, err error) {
-	//fileName := fs.proc.Path("stat")
-	//data, err := ReadFileNoStat(fileName)
-	//if err != nil {
-	//	return Stat{}, err
-	//}
+	_, err := fmt.Sscanf(line, "%s %d %d %d %d %d %d %d %d %d %d %d",
+		&prefix, &total,
+		&softIRQStat.Hi, &softIRQStat.Timer, &softIRQStat.NetTx, &softIRQStat.NetRx,
+		&softIRQStat.Block, &softIRQStat.BlockIoPoll,
+		&softIRQStat.Tasklet, &softIRQStat.Sched,
+		&softIRQStat.Hrtimer, &softIRQStat.Rcu)
 
+	if err != nil {
+		return SoftIRQStat{}, 0, fmt.Errorf("couldn't parse %q (softirq): %w", line, err)
+	}
+
+	return softIRQStat, total, nil
+}
+
+func GetStat(data []byte) (stat Stat, err error) {
 	scanner := bufio.NewScanner(bytes.NewReader(data))
+	// the intr line has a count for every IRQ number the guest kernel
+	// supports, far longer than bufio.MaxScanTokenSize on big guests
+	scanner.Buffer(nil, maxStatLineSize)
 	for scanner.Scan() {
 		line := scanner.Text()
 		parts := strings.Fields(scanner.Text())
@@ -150,44 +137,44 @@ func GetStat(data []byte) (stat Stat, err error) {
 			continue
 		}
 		switch {
-		//case parts[0] == "btime":
-		//	if stat.BootTime, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
-		//		return Stat{}, fmt.Errorf("couldn't parse %q (btime): %w", parts[1], err)
-		//	}
-		//case parts[0] == "intr":
-		//	if stat.IRQTotal, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
-		//		return Stat{}, fmt.Errorf("couldn't parse %q (intr): %w", parts[1], err)
-		//	}
-		//	numberedIRQs := parts[2:]
-		//	stat.IRQ = make([]uint64, len(numberedIRQs))
-		//	for i, count := range numberedIRQs {
-		//		if stat.IRQ[i], err = strconv.ParseUint(count, 10, 64); err != nil {
-		//			return Stat{}, fmt.Errorf("couldn't parse %q (intr%d): %w", count, i, err)
-		//		}
-		//	}
-		//case parts[0] == "ctxt":
-		//	if stat.ContextSwitches, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
-		//		return Stat{}, fmt.Errorf("couldn't parse %q (ctxt): %w", parts[1], err)
-		//	}
-		//case parts[0] == "processes":
-		//	if stat.ProcessCreated, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
-		//		return Stat{}, fmt.Errorf("couldn't parse %q (processes): %w", parts[1], err)
-		//	}
-		//case parts[0] == "procs_running":
-		//	if stat.ProcessesRunning, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
-		//		return Stat{}, fmt.Errorf("couldn't parse %q (procs_running): %w", parts[1], err)
-		//	}
-		//case parts[0] == "procs_blocked":
-		//	if stat.ProcessesBlocked, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
-		//		return Stat{}, fmt.Errorf("couldn't parse %q (procs_blocked): %w", parts[1], err)
-		//	}
-		//case parts[0] == "softirq":
-		//	softIRQStats, total, err := parseSoftIRQStat(line)
-		//	if err != nil {
-		//		return Stat{}, err
-		//	}
-		//	stat.SoftIRQTotal = total
-		//	stat.SoftIRQ = softIRQStats
+		case parts[0] == "btime":
+			if stat.BootTime, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+				return Stat{}, fmt.Errorf("couldn't parse %q (btime): %w", parts[1], err)
+			}
+		case parts[0] == "intr":
+			if stat.IRQTotal, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+				return Stat{}, fmt.Errorf("couldn't parse %q (intr): %w", parts[1], err)
+			}
+			numberedIRQs := parts[2:]
+			stat.IRQ = make([]uint64, len(numberedIRQs))
+			for i, count := range numberedIRQs {
+				if stat.IRQ[i], err = strconv.ParseUint(count, 10, 64); err != nil {
+					return Stat{}, fmt.Errorf("couldn't parse %q (intr%d): %w", count, i, err)
+				}
+			}
+		case parts[0] == "ctxt":
+			if stat.ContextSwitches, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+				return Stat{}, fmt.Errorf("couldn't parse %q (ctxt): %w", parts[1], err)
+			}
+		case parts[0] == "processes":
+			if stat.ProcessCreated, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+				return Stat{}, fmt.Errorf("couldn't parse %q (processes): %w", parts[1], err)
+			}
+		case parts[0] == "procs_running":
+			if stat.ProcessesRunning, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+				return Stat{}, fmt.Errorf("couldn't parse %q (procs_running): %w", parts[1], err)
+			}
+		case parts[0] == "procs_blocked":
+			if stat.ProcessesBlocked, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+				return Stat{}, fmt.Errorf("couldn't parse %q (procs_blocked): %w", parts[1], err)
+			}
+		case parts[0] == "softirq":
+			softIRQStats, total, err := parseSoftIRQStat(line)
+			if err != nil {
+				return Stat{}, err
+			}
+			stat.SoftIRQTotal = total
+			stat.SoftIRQ = softIRQStats
 		case strings.HasPrefix(parts[0], "cpu"):
 			cpuStat, cpuID, err := parseCPUStat(line)
 			if err != nil {
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// /proc/stat of a 2 vCPU guest running Linux 5.15
+const procStat = `cpu  10132153 290696 3084719 46828483 16683 0 25195 0 175628 0
+cpu0 1393280 32966 572056 13343292 6130 0 17875 0 23933 0
+cpu1 1335240 30879 487153 13444736 6250 0 2580 0 20000 0
+intr 199580946 35 9 0 0 0 0 0 0 1 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0
+ctxt 38014093
+btime 1684735382
+processes 26442
+procs_running 2
+procs_blocked 1
+softirq 5057579 250191 1481983 1647 211099 186066 0 1783454 622196 12499 508444
+`
+
+func TestGetStat(t *testing.T) {
+	stat, err := GetStat([]byte(procStat))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Stat{
+		BootTime: 1684735382,
+		CPUTotal: CPUStat{
+			User: 101321.53, Nice: 2906.96, System: 30847.19, Idle: 468284.83,
+			Iowait: 166.83, SoftIRQ: 251.95, Guest: 1756.28,
+		},
+		CPU: []CPUStat{
+			{User: 13932.8, Nice: 329.66, System: 5720.56, Idle: 133432.92, Iowait: 61.3, SoftIRQ: 178.75, Guest: 239.33},
+			{User: 13352.4, Nice: 308.79, System: 4871.53, Idle: 134447.36, Iowait: 62.5, SoftIRQ: 25.8, Guest: 200},
+		},
+		IRQTotal:         199580946,
+		ContextSwitches:  38014093,
+		ProcessCreated:   26442,
+		ProcessesRunning: 2,
+		ProcessesBlocked: 1,
+		SoftIRQTotal:     5057579,
+		SoftIRQ: SoftIRQStat{
+			Hi: 250191, Timer: 1481983, NetTx: 1647, NetRx: 211099, Block: 186066,
+			Tasklet: 1783454, Sched: 622196, Hrtimer: 12499, Rcu: 508444,
+		},
+	}
+	// the numbered IRQs are checked on their own
+	if len(stat.IRQ) != 56 || stat.IRQ[0] != 35 || stat.IRQ[1] != 9 || stat.IRQ[8] != 1 {
+		t.Errorf("unexpected IRQs %v", stat.IRQ)
+	}
+	stat.IRQ = nil
+	if !reflect.DeepEqual(stat, want) {
+		t.Errorf("got %+v, want %+v", stat, want)
+	}
+}
+
+func TestGetStatLongIntrLine(t *testing.T) {
+	// guests with many vCPUs and devices count thousands of IRQ numbers
+	const irqs = 50000
+	data := "cpu  1 2 3 4 5 6 7 8 9 10\nintr 12345" + strings.Repeat(" 0", irqs-1) + " 7\nctxt 42\n"
+	if len(data) < 64*1024 {
+		t.Fatalf("intr line of %d bytes doesn't exceed the default scanner limit", len(data))
+	}
+
+	stat, err := GetStat([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.IRQTotal != 12345 || len(stat.IRQ) != irqs || stat.IRQ[irqs-1] != 7 {
+		t.Errorf("intr line parsed to total %d and %d IRQs", stat.IRQTotal, len(stat.IRQ))
+	}
+	// lines after the intr line are parsed too
+	if stat.ContextSwitches != 42 {
+		t.Errorf("got ctxt %d, want 42", stat.ContextSwitches)
+	}
+}
+
+func TestGetStatOldKernel(t *testing.T) {
+	// Linux 2.6.24 has no guest_nice column and no softirq line
+	stat, err := GetStat([]byte("cpu  100 0 200 300 0 0 0 0 0\nbtime 1200000000\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (CPUStat{User: 1, System: 2, Idle: 3}); stat.CPUTotal != want {
+		t.Errorf("got %+v, want %+v", stat.CPUTotal, want)
+	}
+	if stat.BootTime != 1200000000 || stat.SoftIRQTotal != 0 {
+		t.Errorf("got btime %d, softirq %d", stat.BootTime, stat.SoftIRQTotal)
+	}
+}
+
+func TestGetStatErrors(t *testing.T) {
+	for _, data := range []string{
+		"btime x\n",
+		"intr 10 1 x\n",
+		"ctxt -1\n",
+		"processes 1.5\n",
+		"procs_running x\n",
+		"procs_blocked x\n",
+		"softirq 1 2 3\n",
+		"cpu  x\n",
+		"cpux 1 2 3 4\n",
+	} {
+		if _, err := GetStat([]byte(data)); err == nil {
+			t.Errorf("%q: expected an error", data)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GetVmstat parses /proc/vmstat read from a guest into a map of the counter
+// names to their values.
+func GetVmstat(data []byte) (map[string]uint64, error) {
+	vmstat := map[string]uint64{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// e.g. "pgmajfault 1234"
+		parts := strings.Fields(scanner.Text())
+		if len(parts) != 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse %s in vmstat: %w", parts[0], err)
+		}
+		vmstat[parts[0]] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("couldn't parse vmstat: %w", err)
+	}
+	return vmstat, nil
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"testing"
+)
+
+// /proc/vmstat of a guest running Linux 5.15, shortened
+const procVmstat = `nr_free_pages 78064
+nr_zone_inactive_anon 3861
+nr_dirty 141
+pgpgin 4726604
+pgpgout 14437068
+pswpin 1144
+pswpout 11456
+pgfault 223871931
+pgmajfault 21937
+oom_kill 2
+`
+
+func TestGetVmstat(t *testing.T) {
+	vmstat, err := GetVmstat([]byte(procVmstat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vmstat) != 10 {
+		t.Errorf("got %d counters, want 10", len(vmstat))
+	}
+	for name, want := range map[string]uint64{
+		"pgpgin":     4726604,
+		"pgpgout":    14437068,
+		"pswpin":     1144,
+		"pswpout":    11456,
+		"pgmajfault": 21937,
+		"oom_kill":   2,
+	} {
+		if got, ok := vmstat[name]; !ok || got != want {
+			t.Errorf("%s: got %d (present %t), want %d", name, got, ok, want)
+		}
+	}
+}
+
+func TestGetVmstatWithoutOOMKill(t *testing.T) {
+	// oom_kill was added in Linux 4.13, a missing counter is left out
+	vmstat, err := GetVmstat([]byte("pgpgin 10\npgpgout 20\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := vmstat["oom_kill"]; ok {
+		t.Error("oom_kill reported though missing")
+	}
+}
+
+func TestGetVmstatSkipsMalformedLines(t *testing.T) {
+	vmstat, err := GetVmstat([]byte("\npgpgin\npgpgout 20 kB\npswpin 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vmstat) != 1 || vmstat["pswpin"] != 3 {
+		t.Errorf("got %v, want only pswpin", vmstat)
+	}
+}
+
+func TestGetVmstatBadNumber(t *testing.T) {
+	for _, data := range []string{"pgpgin x\n", "pgpgin -1\n", "pgpgin 18446744073709551616\n"} {
+		if _, err := GetVmstat([]byte(data)); err == nil {
+			t.Errorf("%q: expected an error", data)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package internal
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// SnapshotXML holds the fields of a domain snapshot XML used by the exporter,
+// checkpoints share them apart from State.
+type SnapshotXML struct {
+	Name string `xml:"name"`
+	// e.g. running or shutoff for full state snapshots, disk-snapshot for
+	// disk only snapshots
+	State string `xml:"state"`
+	// in seconds since the Epoch
+	CreationTime int64 `xml:"creationTime"`
+	Parent       struct {
+		Name string `xml:"name"`
+	} `xml:"parent"`
+}
+
+// GetSnapshotXML parses the XML of a domain snapshot or checkpoint.
+func GetSnapshotXML(data string) (SnapshotXML, error) {
+	var s SnapshotXML
+	if err := xml.Unmarshal([]byte(data), &s); err != nil {
+		return SnapshotXML{}, fmt.Errorf("couldn't parse snapshot xml: %w", err)
+	}
+	return s, nil
+}
+
+// DiskOnly tells whether the snapshot holds only the disks, not the memory
+// and device state.
+func (s SnapshotXML) DiskOnly() bool {
+	return s.State == "disk-snapshot"
+}
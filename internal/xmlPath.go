@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// XMLNode is a generic element tree of a document, used to look up values
+// with an XMLPath.
+type XMLNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",chardata"`
+	Nodes   []XMLNode  `xml:",any"`
+}
+
+type xmlPathStep struct {
+	Space    string
+	AnySpace bool
+	Local    string
+	Attr     bool
+}
+
+// XMLPath is the subset of XPath needed to pick values out of the domain XML:
+// an absolute path of element names, where "*" matches any element, ending in
+// an optional "@attribute" or "text()" step.
+type XMLPath []xmlPathStep
+
+// Parse /domain/metadata/app:instance/@id, prefixes are resolved with namespaces.
+func ParseXMLPath(expr string, namespaces map[string]string) (XMLPath, error) {
+	if !strings.HasPrefix(expr, "/") || strings.HasPrefix(expr, "//") {
+		return nil, fmt.Errorf("couldn't parse %q: only absolute paths are supported", expr)
+	}
+	parts := strings.Split(expr[1:], "/")
+	path := XMLPath{}
+	for i, part := range parts {
+		last := i == len(parts)-1
+		step := xmlPathStep{}
+		switch {
+		case part == "text()" && last:
+			continue
+		case strings.HasPrefix(part, "@") && last:
+			step.Attr = true
+			part = part[1:]
+		case part == "" || strings.ContainsAny(part, "[]()@"):
+			return nil, fmt.Errorf("couldn't parse %q: unsupported step %q", expr, part)
+		}
+		if j := strings.Index(part, ":"); j >= 0 {
+			space, ok := namespaces[part[:j]]
+			if !ok {
+				return nil, fmt.Errorf("couldn't parse %q: unknown namespace prefix %q", expr, part[:j])
+			}
+			step.Space = space
+			part = part[j+1:]
+		}
+		step.Local = part
+		// like in XPath, "*" matches elements of any namespace
+		if part == "*" && step.Space == "" {
+			step.AnySpace = true
+		}
+		path = append(path, step)
+	}
+	if len(path) == 0 {
+		return nil, fmt.Errorf("couldn't parse %q: empty path", expr)
+	}
+	return path, nil
+}
+
+// MetadataPath returns the path of key below the custom metadata element of
+// namespace, key is a "/" separated list of element names and may end in an
+// "@attribute". Element names of key match in any namespace, as libvirt only
+// puts the namespace on the top element.
+func MetadataPath(namespace, key string) XMLPath {
+	path := XMLPath{
+		{Local: "domain"},
+		{Local: "metadata"},
+		{Space: namespace, Local: "*"},
+	}
+	if key == "" {
+		return path
+	}
+	for _, part := range strings.Split(key, "/") {
+		step := xmlPathStep{AnySpace: true, Local: part}
+		if strings.HasPrefix(part, "@") {
+			step = xmlPathStep{Local: part[1:], Attr: true}
+		}
+		path = append(path, step)
+	}
+	return path
+}
+
+// Parse a document into an XMLNode tree.
+func GetXMLNode(data string) (XMLNode, error) {
+	node := XMLNode{}
+	if err := xml.Unmarshal([]byte(data), &node); err != nil {
+		return XMLNode{}, fmt.Errorf("couldn't parse xml: %w", err)
+	}
+	return node, nil
+}
+
+// Lookup returns the trimmed text or attribute value of the first match.
+func (n XMLNode) Lookup(path XMLPath) (string, bool) {
+	if len(path) == 0 || !path[0].matches(n.XMLName) {
+		return "", false
+	}
+	return n.lookup(path[1:])
+}
+
+func (n XMLNode) lookup(path XMLPath) (string, bool) {
+	if len(path) == 0 {
+		return strings.TrimSpace(n.Content), true
+	}
+	step := path[0]
+	if step.Attr {
+		for _, attr := range n.Attrs {
+			if step.matches(attr.Name) {
+				return attr.Value, true
+			}
+		}
+		return "", false
+	}
+	for _, child := range n.Nodes {
+		if !step.matches(child.XMLName) {
+			continue
+		}
+		if value, ok := child.lookup(path[1:]); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func (s xmlPathStep) matches(name xml.Name) bool {
+	return (s.Local == "*" || s.Local == name.Local) && (s.AnySpace || s.Space == name.Space)
+}
@@ -0,0 +1,98 @@
+package internal
+
+import "testing"
+
+const testDomainXML = `<domain type='kvm'>
+  <name>vm1</name>
+  <metadata>
+    <app:instance xmlns:app="http://example.org/app/1.0">
+      <app:owner id="42">  alice  </app:owner>
+      <app:project>
+        <app:name>web</app:name>
+      </app:project>
+    </app:instance>
+  </metadata>
+</domain>`
+
+func TestXMLPathLookup(t *testing.T) {
+	namespaces := map[string]string{"app": "http://example.org/app/1.0"}
+	node, err := GetXMLNode(testDomainXML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr  string
+		value string
+		found bool
+	}{
+		{"/domain/name", "vm1", true},
+		{"/domain/name/text()", "vm1", true},
+		{"/domain/@type", "kvm", true},
+		{"/domain/metadata/app:instance/app:owner", "alice", true},
+		{"/domain/metadata/app:instance/app:owner/@id", "42", true},
+		{"/domain/metadata/*/app:project/app:name", "web", true},
+		{"/domain/metadata/app:instance/app:missing", "", false},
+		// without a prefix the element must not be in a namespace
+		{"/domain/metadata/instance/owner", "", false},
+		{"/domain/@missing", "", false},
+		{"/other/name", "", false},
+	}
+	for _, test := range tests {
+		path, err := ParseXMLPath(test.expr, namespaces)
+		if err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+			continue
+		}
+		value, found := node.Lookup(path)
+		if value != test.value || found != test.found {
+			t.Errorf("%s: got %q, %v, want %q, %v", test.expr, value, found, test.value, test.found)
+		}
+	}
+}
+
+func TestParseXMLPathErrors(t *testing.T) {
+	namespaces := map[string]string{"app": "http://example.org/app/1.0"}
+	for _, expr := range []string{
+		"",
+		"domain/name",
+		"//name",
+		"/text()",
+		"/domain//name",
+		"/domain/unknown:instance",
+		"/domain/@type/name",
+		"/domain/text()/name",
+		"/domain/name[1]",
+		"/domain/count(name)",
+	} {
+		if _, err := ParseXMLPath(expr, namespaces); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
+
+func TestMetadataPath(t *testing.T) {
+	node, err := GetXMLNode(testDomainXML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		namespace string
+		key       string
+		value     string
+		found     bool
+	}{
+		{"http://example.org/app/1.0", "owner", "alice", true},
+		{"http://example.org/app/1.0", "owner/@id", "42", true},
+		{"http://example.org/app/1.0", "project/name", "web", true},
+		{"http://example.org/app/1.0", "missing", "", false},
+		{"http://example.org/other/1.0", "owner", "", false},
+	}
+	for _, test := range tests {
+		value, found := node.Lookup(MetadataPath(test.namespace, test.key))
+		if value != test.value || found != test.found {
+			t.Errorf("%s %s: got %q, %v, want %q, %v", test.namespace, test.key, value, found, test.value, test.found)
+		}
+	}
+}
@@ -35,6 +35,18 @@ var (
 		"libvirt.uri",
 		"Libvirt URI from which to extract metrics.",
 	).Default("qemu:///system").String()
+	libvirtKeepaliveInterval = kingpin.Flag(
+		"libvirt.keepalive-interval",
+		"Seconds between keepalive messages on the libvirt connection. Use 0 to disable.",
+	).Default("5").Int()
+	libvirtKeepaliveCount = kingpin.Flag(
+		"libvirt.keepalive-count",
+		"Number of unanswered keepalive messages before the libvirt connection is considered dead.",
+	).Default("5").Uint()
+	libvirtReconnectMaxBackoff = kingpin.Flag(
+		"libvirt.reconnect-max-backoff",
+		"Maximum delay between attempts to reconnect to libvirt.",
+	).Default("1m").Duration()
 	logLevel = kingpin.Flag(
 		"log.level",
 		"log level Debug|Info|Warn|Error",
@@ -44,12 +56,14 @@ var (
 type handler struct {
 	exporterMetricsRegistry *prometheus.Registry
 	unfilteredHandler       http.Handler
-	logger                  logrus.Logger
+	collector               *collector.LibvirtCollector
+	logger                  *logrus.Logger
 }
 
-func newHandler(logger logrus.Logger) *handler {
+func newHandler(lc *collector.LibvirtCollector, logger *logrus.Logger) *handler {
 	h := &handler{
 		exporterMetricsRegistry: prometheus.NewRegistry(),
+		collector:               lc,
 		logger:                  logger,
 	}
 
@@ -90,12 +104,13 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *handler) innerHandler(filters ...string) (http.Handler, error) {
-	lc, err := collector.NewLibvirtCollector(*libvirtURI, h.logger, filters...)
-	if err != nil {
-		return nil, fmt.Errorf("couldn't create collector: %s", err)
-	}
-
-	if len(filters) == 0 {
+	lc := h.collector
+	if len(filters) > 0 {
+		var err error
+		if lc, err = lc.Filter(filters...); err != nil {
+			return nil, fmt.Errorf("couldn't create collector: %s", err)
+		}
+	} else {
 		h.logger.Info("msg=Enabled_collectors")
 		collectors := []string{}
 		for n := range lc.Collectors {
@@ -144,7 +159,12 @@ func main() {
 		logger.SetLevel(logrusLevel)
 	}
 
-	http.Handle(*metricsPath, newHandler(*logger))
+	conn := collector.NewConnection(*libvirtURI, *libvirtKeepaliveInterval, *libvirtKeepaliveCount, *libvirtReconnectMaxBackoff, logger)
+	lc, err := collector.NewLibvirtCollector(conn, logger)
+	if err != nil {
+		logger.Fatal("couldn't create collector: ", err)
+	}
+	http.Handle(*metricsPath, newHandler(lc, logger))
 	// http.Handle(*metricsPath, handler)
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>